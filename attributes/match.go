@@ -0,0 +1,388 @@
+package attributes
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/memsql/scimtools/filter"
+	"github.com/memsql/scimtools/schema"
+)
+
+// Match evaluates the filter against the given resource.
+// The reference schema (and its extensions) define the type and case sensitivity of the attributes.
+// Extension attributes are expected in a complex attribute named after the URN of the extension.
+//
+// Multi valued attributes match if any of their values match, "ne" matches if none of the values are equal.
+// Comparing a complex attribute directly (e.g. `emails co "@example.com"`) compares its "value" sub attribute.
+// Returns an error if the filter references unknown attributes or uses operators not supported by their type.
+func Match(resource map[string]interface{}, expr filter.Expression, s schema.ReferenceSchema, ext ...schema.ReferenceSchema) (bool, error) {
+	m := matcher{s: s, ext: ext}
+	return m.match(resource, nil, expr)
+}
+
+// MatchElement evaluates a value filter against an element of the given (multi valued) complex attribute.
+// The attribute paths within the filter are relative to the attribute, e.g. `type eq "work"` for an email.
+func MatchElement(element map[string]interface{}, expr filter.Expression, attribute *schema.Attribute) (bool, error) {
+	if attribute.Type != schema.ComplexType {
+		return false, fmt.Errorf("attribute %q is not a complex attribute", attribute.Name)
+	}
+	return matcher{}.match(element, attribute.SubAttributes, expr)
+}
+
+type matcher struct {
+	s   schema.ReferenceSchema
+	ext []schema.ReferenceSchema
+
+	// infer indicates that there is no schema, the definitions of the attributes are inferred from their values.
+	// It only applies to the elements of a value path (attrs is not nil), the expression can not refer to the
+	// resource itself. Used to match the elements of attribute paths, see matchingElements.
+	infer bool
+}
+
+// match evaluates the expression, if attrs is not nil the resource is an element of a value path.
+func (m matcher) match(resource map[string]interface{}, attrs []*schema.Attribute, expr filter.Expression) (bool, error) {
+	switch expr := expr.(type) {
+	case *filter.LogicalExpression:
+		left, err := m.match(resource, attrs, expr.Left)
+		if err != nil {
+			return false, err
+		}
+		// Both sides are always evaluated, so invalid filters are reported consistently.
+		right, err := m.match(resource, attrs, expr.Right)
+		if err != nil {
+			return false, err
+		}
+		if expr.Operator == filter.AND {
+			return left && right, nil
+		}
+		return left || right, nil
+	case *filter.GroupExpression:
+		ok, err := m.match(resource, attrs, expr.Expression)
+		if err != nil {
+			return false, err
+		}
+		return ok != expr.Not, nil
+	case *filter.PresentExpression:
+		_, values, err := m.resolve(resource, attrs, expr.AttributePath, false)
+		if err != nil {
+			return false, err
+		}
+		for _, v := range values {
+			if present(v) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case *filter.ComparisonExpression:
+		attribute, values, err := m.resolve(resource, attrs, expr.AttributePath, true)
+		if err != nil {
+			return false, err
+		}
+		return compareAny(attribute, values, expr.Operator, expr.CompareValue)
+	case *filter.ValuePathExpression:
+		if attrs != nil {
+			return false, fmt.Errorf("nested value path: %s", expr)
+		}
+		attribute, values, err := m.resolve(resource, attrs, expr.AttributePath, false)
+		if err != nil {
+			return false, err
+		}
+		if attribute.Type != schema.ComplexType {
+			return false, fmt.Errorf("attribute %q is not a complex attribute", expr.AttributePath)
+		}
+		var matched bool
+		for _, v := range values {
+			element, ok := toMap(v)
+			if !ok {
+				continue
+			}
+			ok, err := m.match(element, attribute.SubAttributes, expr.Filter)
+			if err != nil {
+				return false, err
+			}
+			matched = matched || ok
+		}
+		return matched, nil
+	default:
+		return false, fmt.Errorf("unknown expression: %T", expr)
+	}
+}
+
+// resolve looks up the definition and all values of the attribute the path refers to.
+// Multi valued attributes are flattened, complex attributes are replaced by their "value" sub attribute if compare is set.
+func (m matcher) resolve(resource map[string]interface{}, attrs []*schema.Attribute, path filter.AttributePath, compare bool) (*schema.Attribute, []interface{}, error) {
+	container, attribute, err := m.lookup(resource, attrs, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var values []interface{}
+	if container != nil {
+		if v, ok := Contains(attribute.Name, container); ok {
+			values = ToSlice(v)
+		}
+	}
+
+	sub := path.SubAttribute
+	if sub == "" && compare && attribute.Type == schema.ComplexType {
+		sub = "value"
+	}
+	if sub == "" {
+		return attribute, values, nil
+	}
+
+	subAttribute := FindAttribute(sub, attribute.SubAttributes)
+	if attribute.Type != schema.ComplexType || subAttribute == nil {
+		return nil, nil, fmt.Errorf("unknown attribute: %s.%s", attribute.Name, sub)
+	}
+	var subValues []interface{}
+	for _, v := range values {
+		if element, ok := toMap(v); ok {
+			if v, ok := Contains(subAttribute.Name, element); ok {
+				subValues = append(subValues, ToSlice(v)...)
+			}
+		}
+	}
+	return subAttribute, subValues, nil
+}
+
+// lookup finds the map that contains the attribute and the definition of the attribute.
+// The returned map is nil if the extension of the attribute is not present in the resource.
+func (m matcher) lookup(resource map[string]interface{}, attrs []*schema.Attribute, path filter.AttributePath) (map[string]interface{}, *schema.Attribute, error) {
+	if attrs != nil {
+		if path.URI != "" {
+			return nil, nil, fmt.Errorf("unexpected schema URI in value path: %s", path)
+		}
+		if m.infer {
+			value, _ := Contains(path.AttributeName, resource)
+			return resource, inferAttribute(path.AttributeName, value), nil
+		}
+		if attribute := FindAttribute(path.AttributeName, attrs); attribute != nil {
+			return resource, attribute, nil
+		}
+		return nil, nil, fmt.Errorf("unknown attribute: %s", path)
+	}
+
+	if path.URI == "" || strings.EqualFold(path.URI, m.s.ID) {
+		if attribute := FindAttribute(path.AttributeName, m.s.Attributes); attribute != nil {
+			return resource, attribute, nil
+		}
+		if attribute := FindAttribute(path.AttributeName, schema.CoreAttributes); attribute != nil {
+			return resource, attribute, nil
+		}
+	}
+	for _, e := range m.ext {
+		if path.URI != "" && !strings.EqualFold(path.URI, e.ID) {
+			continue
+		}
+		if attribute := FindAttribute(path.AttributeName, e.Attributes); attribute != nil {
+			var container map[string]interface{}
+			if v, ok := Contains(e.ID, resource); ok {
+				container, _ = toMap(v)
+			}
+			return container, attribute, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unknown attribute: %s", path)
+}
+
+// compareAny checks whether any of the values matches the compare value.
+func compareAny(attribute *schema.Attribute, values []interface{}, op filter.CompareOperator, compareValue interface{}) (bool, error) {
+	if compareValue == nil {
+		switch op {
+		case filter.EQ, filter.NE:
+			var found bool
+			for _, v := range values {
+				found = found || present(v)
+			}
+			return found == (op == filter.NE), nil
+		default:
+			return false, fmt.Errorf("can not compare %q with null using %q", attribute.Name, op)
+		}
+	}
+
+	if op == filter.NE {
+		eq, err := compareAny(attribute, values, filter.EQ, compareValue)
+		return !eq, err
+	}
+
+	var matched bool
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		ok, err := compare(attribute, v, op, compareValue)
+		if err != nil {
+			return false, err
+		}
+		matched = matched || ok
+	}
+	if len(values) == 0 {
+		// Still check whether the comparison is valid for the attribute type.
+		if _, err := compare(attribute, compareValue, op, compareValue); err != nil {
+			return false, err
+		}
+	}
+	return matched, nil
+}
+
+func compare(attribute *schema.Attribute, value interface{}, op filter.CompareOperator, compareValue interface{}) (bool, error) {
+	switch attribute.Type {
+	case schema.BooleanType:
+		b, ok := compareValue.(bool)
+		if !ok {
+			return false, fmt.Errorf("can not compare boolean %q with %v", attribute.Name, compareValue)
+		}
+		if op != filter.EQ {
+			return false, fmt.Errorf("operator %q is not supported for boolean %q", op, attribute.Name)
+		}
+		v, ok := value.(bool)
+		return ok && v == b, nil
+	case schema.IntegerType, schema.DecimalType:
+		f, ok := ToFloat(compareValue)
+		if !ok {
+			return false, fmt.Errorf("can not compare number %q with %v", attribute.Name, compareValue)
+		}
+		v, ok := ToFloat(value)
+		if !ok {
+			return false, nil
+		}
+		return compareOrdered(op, attribute.Name, v == f, v < f)
+	case schema.DateTimeType:
+		str, ok := compareValue.(string)
+		if !ok {
+			return false, fmt.Errorf("can not compare dateTime %q with %v", attribute.Name, compareValue)
+		}
+		v, ok := value.(string)
+		if !ok {
+			return false, nil
+		}
+		switch op {
+		case filter.CO, filter.SW, filter.EW:
+			return compareString(op, v, str, attribute.CaseExact), nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, str)
+		if err != nil {
+			return false, fmt.Errorf("invalid dateTime %q", str)
+		}
+		vt, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return false, nil
+		}
+		return compareOrdered(op, attribute.Name, vt.Equal(t), vt.Before(t))
+	case schema.ComplexType:
+		return false, fmt.Errorf("can not compare complex attribute %q", attribute.Name)
+	default:
+		str, ok := compareValue.(string)
+		if !ok {
+			return false, fmt.Errorf("can not compare string %q with %v", attribute.Name, compareValue)
+		}
+		v, ok := value.(string)
+		if !ok {
+			return false, nil
+		}
+		switch op {
+		case filter.GT, filter.LT, filter.GE, filter.LE:
+			if !attribute.CaseExact {
+				v, str = strings.ToLower(v), strings.ToLower(str)
+			}
+			return compareOrdered(op, attribute.Name, v == str, v < str)
+		}
+		return compareString(op, v, str, attribute.CaseExact), nil
+	}
+}
+
+func compareOrdered(op filter.CompareOperator, name string, eq, lt bool) (bool, error) {
+	switch op {
+	case filter.EQ:
+		return eq, nil
+	case filter.GT:
+		return !eq && !lt, nil
+	case filter.GE:
+		return !lt, nil
+	case filter.LT:
+		return lt, nil
+	case filter.LE:
+		return eq || lt, nil
+	default:
+		return false, fmt.Errorf("operator %q is not supported for %q", op, name)
+	}
+}
+
+func compareString(op filter.CompareOperator, value, compareValue string, caseExact bool) bool {
+	if !caseExact {
+		value, compareValue = strings.ToLower(value), strings.ToLower(compareValue)
+	}
+	switch op {
+	case filter.EQ:
+		return value == compareValue
+	case filter.CO:
+		return strings.Contains(value, compareValue)
+	case filter.SW:
+		return strings.HasPrefix(value, compareValue)
+	case filter.EW:
+		return strings.HasSuffix(value, compareValue)
+	default:
+		return false
+	}
+}
+
+// FindAttribute returns the attribute with the given name (case insensitive), nil if not found.
+func FindAttribute(name string, attrs []*schema.Attribute) *schema.Attribute {
+	for _, attribute := range attrs {
+		if strings.EqualFold(name, attribute.Name) {
+			return attribute
+		}
+	}
+	return nil
+}
+
+// inferAttribute creates a definition of the attribute based on its value, strings are never case exact.
+func inferAttribute(name string, value interface{}) *schema.Attribute {
+	attribute := &schema.Attribute{Name: name, Type: schema.StringType}
+	for _, v := range ToSlice(value) {
+		if _, ok := v.(bool); ok {
+			attribute.Type = schema.BooleanType
+		} else if _, ok := ToFloat(v); ok {
+			attribute.Type = schema.DecimalType
+		} else if v == nil {
+			continue
+		}
+		break
+	}
+	return attribute
+}
+
+// present checks whether the value is not nil, an empty string or an empty slice/map.
+func present(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() != 0
+	case reflect.Ptr, reflect.Interface:
+		return !v.IsNil()
+	default:
+		return true
+	}
+}
+
+// toMap converts any map with string keys into a map[string]interface{}.
+func toMap(value interface{}) (map[string]interface{}, bool) {
+	if m, ok := value.(map[string]interface{}); ok {
+		return m, true
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	m := make(map[string]interface{}, v.Len())
+	for _, k := range v.MapKeys() {
+		m[k.String()] = v.MapIndex(k).Interface()
+	}
+	return m, true
+}
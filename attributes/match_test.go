@@ -0,0 +1,154 @@
+package attributes_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/memsql/scimtools/attributes"
+	"github.com/memsql/scimtools/filter"
+	"github.com/memsql/scimtools/schema"
+)
+
+var (
+	userSchema = schema.ReferenceSchema{
+		ID: "urn:ietf:params:scim:schemas:core:2.0:User",
+		Attributes: []*schema.Attribute{
+			{Name: "userName", Type: schema.StringType},
+			{Name: "title", Type: schema.StringType},
+			{Name: "nickName", Type: schema.StringType, CaseExact: true},
+			{Name: "active", Type: schema.BooleanType},
+			{Name: "loginCount", Type: schema.IntegerType},
+			{Name: "lastLogin", Type: schema.DateTimeType},
+			{
+				Name:        "emails",
+				Type:        schema.ComplexType,
+				MultiValued: true,
+				SubAttributes: []*schema.Attribute{
+					{Name: "value", Type: schema.StringType},
+					{Name: "type", Type: schema.StringType},
+					{Name: "primary", Type: schema.BooleanType},
+				},
+			},
+		},
+	}
+	enterpriseSchema = schema.ReferenceSchema{
+		ID: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+		Attributes: []*schema.Attribute{
+			{Name: "employeeNumber", Type: schema.StringType},
+		},
+	}
+)
+
+func ExampleMatch() {
+	resource := map[string]interface{}{
+		"userName": "BJensen",
+		"emails": []interface{}{
+			map[string]interface{}{"value": "bjensen@example.com", "type": "work"},
+			map[string]interface{}{"value": "babs@jensen.org", "type": "home"},
+		},
+	}
+
+	expr, _ := filter.Parse(`userName eq "bjensen" and emails[type eq "work" and value co "@example.com"]`)
+	fmt.Println(attributes.Match(resource, expr, userSchema))
+
+	// Output:
+	// true <nil>
+}
+
+func ExampleFindAttribute() {
+	fmt.Println(attributes.FindAttribute("USERNAME", userSchema.Attributes).Name)
+	fmt.Println(attributes.FindAttribute("nickName", enterpriseSchema.Attributes))
+
+	// Output:
+	// userName
+	// <nil>
+}
+
+func TestMatch(t *testing.T) {
+	resource := map[string]interface{}{
+		"id":         "2819c223",
+		"UserName":   "BJensen",
+		"nickName":   "Babs",
+		"title":      nil,
+		"active":     true,
+		"loginCount": int64(42),
+		"lastLogin":  "2011-05-13T04:42:34+02:00",
+		"emails": []map[string]interface{}{
+			{"value": "bjensen@example.com", "type": "work", "primary": true},
+			{"value": "babs@jensen.org", "type": "home"},
+		},
+		enterpriseSchema.ID: map[string]interface{}{
+			"employeeNumber": "701984",
+		},
+	}
+
+	for _, test := range []struct {
+		filter string
+		match  bool
+	}{
+		{filter: `userName eq "bjensen"`, match: true},
+		{filter: `userName sw "BJ"`, match: true},
+		{filter: `userName ne "bjensen"`, match: false},
+		{filter: `userName gt "a" and userName lt "c"`, match: true},
+		{filter: `nickName eq "babs"`, match: false},
+		{filter: `nickName eq "Babs"`, match: true},
+		{filter: `id eq "2819c223"`, match: true},
+		{filter: `active eq true`, match: true},
+		{filter: `active ne false`, match: true},
+		{filter: `loginCount ge 42`, match: true},
+		{filter: `loginCount gt 42.5`, match: false},
+		{filter: `lastLogin gt "2011-05-13T02:00:00Z"`, match: true},
+		{filter: `lastLogin lt "2011-05-13T03:00:00Z"`, match: true},
+		{filter: `emails.type eq "home"`, match: true},
+		{filter: `emails.type ne "work"`, match: false},
+		{filter: `emails co "@jensen.org"`, match: true},
+		{filter: `emails[type eq "home" and primary eq true]`, match: false},
+		{filter: `emails[type eq "work" and primary eq true]`, match: true},
+		{filter: `emails[type eq "home"] and not (emails pr)`, match: false},
+		{filter: `title pr`, match: false},
+		{filter: `emails.primary pr`, match: true},
+		{filter: `employeeNumber eq "701984"`, match: true},
+		{filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber sw "70"`, match: true},
+		{filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName eq "bjensen"`, match: true},
+		{filter: `userName eq null`, match: false},
+		{filter: `title eq null`, match: true},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			expr, err := filter.Parse(test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ok, err := attributes.Match(resource, expr, userSchema, enterpriseSchema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != test.match {
+				t.Errorf("expected %t, got %t", test.match, ok)
+			}
+		})
+	}
+}
+
+func TestMatch_invalid(t *testing.T) {
+	for _, filterString := range []string{
+		`unknown eq "x"`,
+		`emails.unknown eq "x"`,
+		`active gt true`,
+		`active eq "true"`,
+		`loginCount co 1`,
+		`userName eq 1`,
+		`userName[value pr]`,
+		`lastLogin gt "yesterday"`,
+		`urn:unknown:userName eq "x"`,
+	} {
+		t.Run(filterString, func(t *testing.T) {
+			expr, err := filter.Parse(filterString)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := attributes.Match(map[string]interface{}{}, expr, userSchema, enterpriseSchema); err == nil {
+				t.Error("error expected, got none")
+			}
+		})
+	}
+}
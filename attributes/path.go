@@ -0,0 +1,286 @@
+package attributes
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/memsql/scimtools/filter"
+	"github.com/memsql/scimtools/schema"
+)
+
+// GetPath searches the given map for the value the attribute path refers to. This lookup is case insensitive!
+// e.g. "name.givenName", `emails[type eq "work"].value` or "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager"
+//
+// Paths with a value filter, or a sub attribute of a multi valued attribute, return a []interface{} of all matching values.
+// A schema URI refers to the extension stored in the complex attribute with that name,
+// the URIs of core schemas (e.g. "urn:ietf:params:scim:schemas:core:2.0:User") refer to the resource itself.
+// Value filters are evaluated without schema, so string comparisons are never case exact.
+func GetPath(path string, a map[string]interface{}) (interface{}, error) {
+	p, err := filter.ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	container, name := pathContainer(a, p.AttributePath, false)
+	if container == nil {
+		return nil, errNotFound(path)
+	}
+	value, found := Contains(name, container)
+	if !found || value == nil {
+		return nil, errNotFound(path)
+	}
+
+	sub := p.AttributePath.SubAttribute
+	if p.ValueFilter == nil && sub == "" {
+		return value, nil
+	}
+
+	elements := []interface{}{value}
+	if p.ValueFilter != nil || isSlice(value) {
+		indexes, err := matchingElements(value, p.ValueFilter)
+		if err != nil {
+			return nil, err
+		}
+		elements = make([]interface{}, len(indexes))
+		for i, index := range indexes {
+			elements[i] = reflect.ValueOf(value).Index(index).Interface()
+		}
+	}
+	if sub == "" {
+		if len(elements) == 0 {
+			return nil, errNotFound(path)
+		}
+		return elements, nil
+	}
+
+	var values []interface{}
+	for _, e := range elements {
+		m, ok := toMap(e)
+		if !ok {
+			return nil, errInvalid(name, "complex attribute")
+		}
+		if v, ok := Contains(sub, m); ok && v != nil {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return nil, errNotFound(path)
+	}
+	if p.ValueFilter == nil && !isSlice(value) {
+		return values[0], nil
+	}
+	return values, nil
+}
+
+// SetPath stores the given value at the attribute path, overwriting existing values.
+// Missing complex attributes (and extensions) are created, existing keys keep their casing.
+// If the path contains a value filter, or refers to a sub attribute of a multi valued attribute,
+// the value is set on all the matching elements (nil clears them). Returns an error if no element matches the filter.
+func SetPath(path string, a map[string]interface{}, value interface{}) error {
+	p, err := filter.ParsePath(path)
+	if err != nil {
+		return err
+	}
+	container, name := pathContainer(a, p.AttributePath, true)
+	if container == nil {
+		return errInvalid(p.AttributePath.URI, "complex attribute")
+	}
+	name = ExistingKey(name, container)
+
+	sub := p.AttributePath.SubAttribute
+	if p.ValueFilter == nil && sub == "" {
+		container[name] = value
+		return nil
+	}
+
+	current, found := container[name]
+	if !found && p.ValueFilter != nil {
+		return errNotFound(path)
+	}
+	if p.ValueFilter == nil && (!found || !isSlice(current)) {
+		m := EnsureComplexAttribute(container, name)
+		m[ExistingKey(sub, m)] = value
+		return nil
+	}
+
+	indexes, err := matchingElements(current, p.ValueFilter)
+	if err != nil {
+		return err
+	}
+	if len(indexes) == 0 {
+		if p.ValueFilter == nil {
+			return nil
+		}
+		return errNotFound(path)
+	}
+	slice := reflect.ValueOf(current)
+	for _, i := range indexes {
+		element := slice.Index(i)
+		if sub == "" {
+			v := reflect.ValueOf(value)
+			if value == nil && isNillable(element.Kind()) {
+				v = reflect.Zero(element.Type())
+			}
+			if !element.CanSet() || !v.IsValid() || !v.Type().AssignableTo(element.Type()) {
+				return errInvalid(name, fmt.Sprintf("slice of %T", value))
+			}
+			element.Set(v)
+			continue
+		}
+		m, ok := element.Interface().(map[string]interface{})
+		if !ok {
+			return errInvalid(name, "complex attribute")
+		}
+		m[ExistingKey(sub, m)] = value
+	}
+	return nil
+}
+
+// DeletePath removes the value the attribute path refers to.
+// If the path contains a value filter, the matching elements (or their sub attribute) are removed.
+// Returns an error if the attribute, or any element matching the filter, is not found.
+func DeletePath(path string, a map[string]interface{}) error {
+	p, err := filter.ParsePath(path)
+	if err != nil {
+		return err
+	}
+	container, name := pathContainer(a, p.AttributePath, false)
+	if container == nil {
+		return errNotFound(path)
+	}
+	name = ExistingKey(name, container)
+	current, found := container[name]
+	if !found {
+		return errNotFound(path)
+	}
+
+	sub := p.AttributePath.SubAttribute
+	if p.ValueFilter == nil && sub == "" {
+		delete(container, name)
+		return nil
+	}
+	if p.ValueFilter == nil && !isSlice(current) {
+		m, ok := toMap(current)
+		if !ok {
+			return errInvalid(name, "complex attribute")
+		}
+		if _, ok := Contains(sub, m); !ok {
+			return errNotFound(path)
+		}
+		delete(m, ExistingKey(sub, m))
+		return nil
+	}
+
+	indexes, err := matchingElements(current, p.ValueFilter)
+	if err != nil {
+		return err
+	}
+	if len(indexes) == 0 {
+		return errNotFound(path)
+	}
+	slice := reflect.ValueOf(current)
+	if sub != "" {
+		for _, i := range indexes {
+			m, ok := toMap(slice.Index(i).Interface())
+			if !ok {
+				return errInvalid(name, "complex attribute")
+			}
+			delete(m, ExistingKey(sub, m))
+		}
+		return nil
+	}
+
+	remaining := reflect.MakeSlice(reflect.SliceOf(slice.Type().Elem()), 0, slice.Len()-len(indexes))
+	for i, j := 0, 0; i < slice.Len(); i++ {
+		if j < len(indexes) && indexes[j] == i {
+			j++
+			continue
+		}
+		remaining = reflect.Append(remaining, slice.Index(i))
+	}
+	if remaining.Len() == 0 {
+		delete(container, name)
+		return nil
+	}
+	container[name] = remaining.Interface()
+	return nil
+}
+
+// coreSchemaPrefix is the prefix of the URIs of the core schemas, of which the attributes are stored in the resource
+// itself instead of a complex attribute named after the URI.
+const coreSchemaPrefix = "urn:ietf:params:scim:schemas:core:"
+
+// pathContainer returns the map that holds the attribute and the name of the attribute within that map.
+// If create is set, missing extensions are created. Returns nil if the extension is not present.
+func pathContainer(resource map[string]interface{}, path filter.AttributePath, create bool) (map[string]interface{}, string) {
+	if path.URI == "" {
+		return resource, path.AttributeName
+	}
+
+	// The path refers to the extension itself.
+	full := path.URI + ":" + path.AttributeName
+	if _, ok := Contains(full, resource); ok && path.SubAttribute == "" {
+		return resource, full
+	}
+
+	if value, ok := Contains(path.URI, resource); ok {
+		if m, ok := toMap(value); ok {
+			return m, path.AttributeName
+		}
+		return nil, path.AttributeName
+	}
+	if strings.HasPrefix(strings.ToLower(path.URI), coreSchemaPrefix) {
+		return resource, path.AttributeName
+	}
+	if create {
+		return EnsureComplexAttribute(resource, path.URI), path.AttributeName
+	}
+	return nil, path.AttributeName
+}
+
+// matchingElements returns the indexes of the elements of the slice that match the filter.
+// All elements match if the filter is nil.
+//
+// There is no schema, so the elements are matched like the elements of a value path without sub attributes: the
+// attributes in the filter are inferred from the values of the element (see matcher.infer).
+func matchingElements(value interface{}, expr filter.Expression) ([]int, error) {
+	if !isSlice(value) {
+		return nil, fmt.Errorf("attribute is not multi valued: %v", value)
+	}
+	m := matcher{infer: true}
+	noSubAttributes := []*schema.Attribute{}
+	slice := reflect.ValueOf(value)
+	var indexes []int
+	for i := 0; i < slice.Len(); i++ {
+		if expr == nil {
+			indexes = append(indexes, i)
+			continue
+		}
+		element, ok := toMap(slice.Index(i).Interface())
+		if !ok {
+			continue
+		}
+		ok, err := m.match(element, noSubAttributes, expr)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
+
+func isSlice(value interface{}) bool {
+	k := reflect.ValueOf(value).Kind()
+	return k == reflect.Slice || k == reflect.Array
+}
+
+func isNillable(k reflect.Kind) bool {
+	switch k {
+	case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,193 @@
+package attributes_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/memsql/scimtools/attributes"
+)
+
+const enterpriseURN = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+
+func newPathResource() map[string]interface{} {
+	return map[string]interface{}{
+		"userName": "bjensen",
+		"name": map[string]interface{}{
+			"givenName": "Barbara",
+		},
+		"emails": []interface{}{
+			map[string]interface{}{"value": "bjensen@example.com", "type": "work"},
+			map[string]interface{}{"value": "babs@jensen.org", "type": "home"},
+		},
+		enterpriseURN: map[string]interface{}{
+			"manager": map[string]interface{}{"value": "26118915"},
+		},
+	}
+}
+
+func ExampleGetPath() {
+	resource := newPathResource()
+
+	fmt.Println(attributes.GetPath("Name.givenName", resource))
+	fmt.Println(attributes.GetPath(`emails[type eq "work"].value`, resource))
+	fmt.Println(attributes.GetPath(enterpriseURN+":manager.value", resource))
+	fmt.Println(attributes.GetPath("nickName", resource))
+
+	// Output:
+	// Barbara <nil>
+	// [bjensen@example.com] <nil>
+	// 26118915 <nil>
+	// <nil> could not find "nickName" in attributes
+}
+
+func ExampleSetPath() {
+	resource := newPathResource()
+
+	_ = attributes.SetPath("name.familyName", resource, "Jensen")
+	_ = attributes.SetPath(`emails[type eq "home"].primary`, resource, true)
+	_ = attributes.SetPath(enterpriseURN+":employeeNumber", resource, "701984")
+
+	fmt.Println(resource["name"])
+	fmt.Println(resource["emails"])
+	fmt.Println(resource[enterpriseURN])
+
+	// Output:
+	// map[familyName:Jensen givenName:Barbara]
+	// [map[type:work value:bjensen@example.com] map[primary:true type:home value:babs@jensen.org]]
+	// map[employeeNumber:701984 manager:map[value:26118915]]
+}
+
+func ExampleDeletePath() {
+	resource := newPathResource()
+
+	_ = attributes.DeletePath("name.givenName", resource)
+	_ = attributes.DeletePath(`emails[type eq "work"]`, resource)
+	_ = attributes.DeletePath(enterpriseURN, resource)
+
+	fmt.Println(resource)
+
+	// Output:
+	// map[emails:[map[type:home value:babs@jensen.org]] name:map[] userName:bjensen]
+}
+
+func TestGetPath(t *testing.T) {
+	resource := newPathResource()
+	for path, expected := range map[string]interface{}{
+		"USERNAME":                 "bjensen",
+		"emails.type":              []interface{}{"work", "home"},
+		`emails[value ew ".ORG"]`:  []interface{}{map[string]interface{}{"value": "babs@jensen.org", "type": "home"}},
+		enterpriseURN + ":manager": map[string]interface{}{"value": "26118915"},
+		enterpriseURN:              map[string]interface{}{"manager": map[string]interface{}{"value": "26118915"}},
+		"urn:ietf:params:scim:schemas:core:2.0:User:userName": "bjensen",
+	} {
+		value, err := attributes.GetPath(path, resource)
+		if err != nil {
+			t.Errorf("%s: %s", path, err)
+			continue
+		}
+		if !reflect.DeepEqual(value, expected) {
+			t.Errorf("%s: expected %v, got %v", path, expected, value)
+		}
+	}
+
+	for _, path := range []string{
+		"name.familyName",
+		`emails[type eq "other"]`,
+		`emails[type eq "work"].display`,
+		`emails[type eq`,
+	} {
+		if _, err := attributes.GetPath(path, resource); err == nil {
+			t.Errorf("%s: error expected, got none", path)
+		}
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	resource := newPathResource()
+	if err := attributes.SetPath("UserName", resource, "babs"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resource["UserName"]; ok || resource["userName"] != "babs" {
+		t.Errorf("expected existing key to be overwritten, got %v", resource)
+	}
+
+	if err := attributes.SetPath(`emails[type eq "other"].value`, resource, "x"); err == nil {
+		t.Error("error expected, got none")
+	}
+
+	if err := attributes.SetPath("emails.primary", resource, false); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := attributes.GetPath("emails.primary", resource); !reflect.DeepEqual(v, []interface{}{false, false}) {
+		t.Errorf("expected primary to be set on all emails, got %v", v)
+	}
+
+	if err := attributes.SetPath(`emails[type eq "home"]`, resource, nil); err != nil {
+		t.Fatal(err)
+	}
+	if emails := resource["emails"].([]interface{}); len(emails) != 2 || emails[0] == nil || emails[1] != nil {
+		t.Errorf("expected the home email to be nil, got %v", emails)
+	}
+	resource["emails"] = []string{"bjensen@example.com"}
+	if err := attributes.SetPath("emails", resource, nil); err != nil || resource["emails"] != nil {
+		t.Errorf("expected emails to be nil, got %v: %v", resource["emails"], err)
+	}
+	resource["emails"] = []map[string]interface{}{{"value": "bjensen@example.com"}}
+	if err := attributes.SetPath(`emails[value pr]`, resource, nil); err != nil {
+		t.Fatal(err)
+	}
+	if emails := resource["emails"].([]map[string]interface{}); emails[0] != nil {
+		t.Errorf("expected the email to be nil, got %v", emails)
+	}
+}
+
+func TestDeletePath(t *testing.T) {
+	resource := newPathResource()
+	for _, path := range []string{
+		"nickName",
+		"name.familyName",
+		`emails[type eq "other"]`,
+	} {
+		if err := attributes.DeletePath(path, resource); err == nil {
+			t.Errorf("%s: error expected, got none", path)
+		}
+	}
+
+	if err := attributes.DeletePath(`emails[value co "@"]`, resource); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resource["emails"]; ok {
+		t.Error("expected emails to be removed")
+	}
+}
+
+func TestPath_absentExtension(t *testing.T) {
+	const path = "urn:ietf:params:scim:schemas:extension:custom:2.0:User:userName"
+
+	resource := newPathResource()
+	if v, err := attributes.GetPath(path, resource); err == nil {
+		t.Errorf("error expected, got %v", v)
+	}
+	if err := attributes.DeletePath(path, resource); err == nil {
+		t.Error("error expected, got none")
+	}
+	if resource["userName"] != "bjensen" {
+		t.Errorf("expected userName to be untouched, got %v", resource)
+	}
+
+	if err := attributes.SetPath(path, resource, "babs"); err != nil {
+		t.Fatal(err)
+	}
+	extension := map[string]interface{}{"userName": "babs"}
+	if resource["userName"] != "bjensen" || !reflect.DeepEqual(resource["urn:ietf:params:scim:schemas:extension:custom:2.0:User"], extension) {
+		t.Errorf("expected the extension to be created, got %v", resource)
+	}
+
+	if err := attributes.SetPath("urn:ietf:params:scim:schemas:core:2.0:User:userName", resource, "babs"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resource["urn:ietf:params:scim:schemas:core:2.0:User"]; ok || resource["userName"] != "babs" {
+		t.Errorf("expected userName to be set, got %v", resource)
+	}
+}
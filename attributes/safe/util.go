@@ -30,6 +30,13 @@ func GetMap(id string, a map[string]interface{}) map[string]interface{} {
 	return m
 }
 
+// GetPath searches the given map for the value the attribute path refers to.
+// Returns nil if the path is invalid or not found.
+func GetPath(path string, a map[string]interface{}) interface{} {
+	v, _ := attributes.GetPath(path, a)
+	return v
+}
+
 // GetString searches the given map for a string that matches the given id.
 // Returns an empty string if not found.
 func GetString(id string, a map[string]interface{}) string {
@@ -1,7 +1,9 @@
 package attributes
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 )
 
@@ -101,3 +103,55 @@ func GetStringInSubMap(mID, sID string, a map[string]interface{}) (string, error
 	}
 	return str, nil
 }
+
+// ExistingKey returns the key in the map that matches the given key case insensitively, or the key itself.
+func ExistingKey(key string, a map[string]interface{}) string {
+	if _, ok := a[key]; ok {
+		return key
+	}
+	for k := range a {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+	}
+	return key
+}
+
+// ToSlice returns the values of a multi valued attribute: the elements of any slice or array, other values are
+// wrapped in a slice. Returns nil for nil.
+func ToSlice(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []interface{}{value}
+	}
+	values := make([]interface{}, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+	return values
+}
+
+// ToFloat converts any number (including json.Number) into a float64.
+func ToFloat(value interface{}) (float64, bool) {
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
@@ -1,6 +1,7 @@
 package attributes_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/memsql/scimtools/attributes"
 )
@@ -121,3 +122,40 @@ func ExampleGetStringInSubMap() {
 	//  could not find "z" in attributes
 	//  could not find "y" in attributes
 }
+
+func ExampleExistingKey() {
+	attrs := map[string]interface{}{
+		"userName": "di-wu",
+	}
+
+	fmt.Println(attributes.ExistingKey("USERNAME", attrs))
+	fmt.Println(attributes.ExistingKey("nickName", attrs))
+
+	// Output:
+	// userName
+	// nickName
+}
+
+func ExampleToSlice() {
+	fmt.Println(attributes.ToSlice([]string{"a", "b"}))
+	fmt.Println(attributes.ToSlice([2]int{1, 2}))
+	fmt.Println(attributes.ToSlice("a"))
+	fmt.Println(attributes.ToSlice(nil) == nil)
+
+	// Output:
+	// [a b]
+	// [1 2]
+	// [a]
+	// true
+}
+
+func ExampleToFloat() {
+	fmt.Println(attributes.ToFloat(json.Number("1.5")))
+	fmt.Println(attributes.ToFloat(uint8(2)))
+	fmt.Println(attributes.ToFloat("3"))
+
+	// Output:
+	// 1.5 true
+	// 2 true
+	// 0 false
+}
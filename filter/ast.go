@@ -0,0 +1,156 @@
+// Package filter parses SCIM filter expressions as defined in RFC 7644 §3.4.2.2.
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Expression is a node of a parsed filter.
+type Expression interface {
+	fmt.Stringer
+	expression()
+}
+
+// AttributePath is a reference to an attribute, optionally prefixed by the URI of the schema it is defined in.
+// e.g. "urn:ietf:params:scim:schemas:core:2.0:User:name.givenName"
+type AttributePath struct {
+	URI           string
+	AttributeName string
+	SubAttribute  string
+}
+
+func (p AttributePath) String() string {
+	var s string
+	if p.URI != "" {
+		s = p.URI + ":"
+	}
+	s += p.AttributeName
+	if p.SubAttribute != "" {
+		s += "." + p.SubAttribute
+	}
+	return s
+}
+
+// CompareOperator is an operator that compares an attribute with a value.
+type CompareOperator string
+
+const (
+	EQ CompareOperator = "eq"
+	NE CompareOperator = "ne"
+	CO CompareOperator = "co"
+	SW CompareOperator = "sw"
+	EW CompareOperator = "ew"
+	GT CompareOperator = "gt"
+	LT CompareOperator = "lt"
+	GE CompareOperator = "ge"
+	LE CompareOperator = "le"
+)
+
+// LogicalOperator is an operator that combines two expressions.
+type LogicalOperator string
+
+const (
+	AND LogicalOperator = "and"
+	OR  LogicalOperator = "or"
+)
+
+// ComparisonExpression compares the value of an attribute with the given value.
+// The compare value is either a string, bool, int, float64 or nil (null).
+type ComparisonExpression struct {
+	AttributePath AttributePath
+	Operator      CompareOperator
+	CompareValue  interface{}
+}
+
+func (e *ComparisonExpression) String() string {
+	return fmt.Sprintf("%s %s %s", e.AttributePath, e.Operator, formatValue(e.CompareValue))
+}
+
+// PresentExpression matches if the attribute has a non empty value.
+type PresentExpression struct {
+	AttributePath AttributePath
+}
+
+func (e *PresentExpression) String() string {
+	return fmt.Sprintf("%s pr", e.AttributePath)
+}
+
+// LogicalExpression combines two expressions with a logical operator.
+type LogicalExpression struct {
+	Operator LogicalOperator
+	Left     Expression
+	Right    Expression
+}
+
+func (e *LogicalExpression) String() string {
+	return fmt.Sprintf("%s %s %s", e.operand(e.Left), e.Operator, e.operand(e.Right))
+}
+
+// operand formats a child expression, adding parentheses if the child binds weaker than its parent.
+func (e *LogicalExpression) operand(child Expression) string {
+	if l, ok := child.(*LogicalExpression); ok && e.Operator == AND && l.Operator == OR {
+		return "(" + l.String() + ")"
+	}
+	return child.String()
+}
+
+// GroupExpression is an expression within parentheses, optionally negated.
+type GroupExpression struct {
+	Not        bool
+	Expression Expression
+}
+
+func (e *GroupExpression) String() string {
+	if e.Not {
+		return fmt.Sprintf("not (%s)", e.Expression)
+	}
+	return fmt.Sprintf("(%s)", e.Expression)
+}
+
+// ValuePathExpression matches if any value of the (multi valued) complex attribute matches the filter.
+// The attribute paths within the filter are relative to the attribute.
+type ValuePathExpression struct {
+	AttributePath AttributePath
+	Filter        Expression
+}
+
+func (e *ValuePathExpression) String() string {
+	return fmt.Sprintf("%s[%s]", e.AttributePath, e.Filter)
+}
+
+func (*ComparisonExpression) expression() {}
+func (*PresentExpression) expression()    {}
+func (*LogicalExpression) expression()    {}
+func (*GroupExpression) expression()      {}
+func (*ValuePathExpression) expression()  {}
+
+// formatValue formats a compare value as a JSON literal.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		// Keep the decimal point so the value is not parsed back as an integer.
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case string:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(v)
+		return strings.TrimSuffix(buf.String(), "\n")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,112 @@
+package filter
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenWord
+	tokenString
+	tokenOpenParen
+	tokenCloseParen
+	tokenOpenBracket
+	tokenCloseBracket
+)
+
+func (t tokenType) String() string {
+	switch t {
+	case tokenEOF:
+		return "end of filter"
+	case tokenWord:
+		return "word"
+	case tokenString:
+		return "string"
+	case tokenOpenParen:
+		return `"("`
+	case tokenCloseParen:
+		return `")"`
+	case tokenOpenBracket:
+		return `"["`
+	case tokenCloseBracket:
+		return `"]"`
+	default:
+		return "unknown token"
+	}
+}
+
+type token struct {
+	typ    tokenType
+	value  string
+	offset int
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tokenWord, tokenString:
+		return fmt.Sprintf("%q", t.value)
+	default:
+		return t.typ.String()
+	}
+}
+
+// lex splits the given filter into tokens, the last token is always tokenEOF.
+func lex(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{typ: tokenOpenParen, value: "(", offset: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{typ: tokenCloseParen, value: ")", offset: i})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{typ: tokenOpenBracket, value: "[", offset: i})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{typ: tokenCloseBracket, value: "]", offset: i})
+			i++
+		case c == '"':
+			start := i
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+			if len(s) <= i {
+				return nil, &SyntaxError{Offset: start, Msg: "unterminated string"}
+			}
+			i++
+			tokens = append(tokens, token{typ: tokenString, value: s[start:i], offset: start})
+		case isWordChar(c):
+			start := i
+			for i < len(s) && isWordChar(s[i]) {
+				i++
+			}
+			tokens = append(tokens, token{typ: tokenWord, value: s[start:i], offset: start})
+		default:
+			r, _ := utf8.DecodeRuneInString(s[i:])
+			return nil, &SyntaxError{Offset: i, Msg: fmt.Sprintf("unexpected character %q", r)}
+		}
+	}
+	return append(tokens, token{typ: tokenEOF, offset: len(s)}), nil
+}
+
+// isWordChar checks whether the character can be part of an attribute path, keyword or number.
+func isWordChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	case c == ':', c == '.', c == '-', c == '_', c == '$', c == '+':
+		return true
+	default:
+		return false
+	}
+}
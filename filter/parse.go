@@ -0,0 +1,271 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SyntaxError describes why a filter could not be parsed.
+// It should be reported to the client as an "invalidFilter" error.
+type SyntaxError struct {
+	// Offset is the byte offset within the filter where the error occurred.
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid filter at offset %d: %s", e.Offset, e.Msg)
+}
+
+// Parse parses the given filter into an expression.
+// Keywords and operators are case insensitive.
+func Parse(filter string) (Expression, error) {
+	tokens, err := lex(filter)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{tokens: tokens}
+	expr, err := p.parseFilter(false)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != tokenEOF {
+		return nil, p.unexpected(t)
+	}
+	return expr, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(typ tokenType) (token, error) {
+	t := p.next()
+	if t.typ != typ {
+		return t, &SyntaxError{Offset: t.offset, Msg: fmt.Sprintf("expected %s, got %s", typ, t)}
+	}
+	return t, nil
+}
+
+func (p *parser) unexpected(t token) error {
+	return &SyntaxError{Offset: t.offset, Msg: fmt.Sprintf("unexpected %s", t)}
+}
+
+// isKeyword checks whether the next token is the given (case insensitive) keyword.
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.typ == tokenWord && strings.EqualFold(t.value, keyword)
+}
+
+// parseFilter parses a sequence of expressions joined by "or".
+// Within a value filter, nested value paths are not allowed.
+func (p *parser) parseFilter(valueFilter bool) (Expression, error) {
+	left, err := p.parseAnd(valueFilter)
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword(string(OR)) {
+		p.next()
+		right, err := p.parseAnd(valueFilter)
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpression{Operator: OR, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// parseAnd parses a sequence of expressions joined by "and", which takes precedence over "or".
+func (p *parser) parseAnd(valueFilter bool) (Expression, error) {
+	left, err := p.parseExpression(valueFilter)
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword(string(AND)) {
+		p.next()
+		right, err := p.parseExpression(valueFilter)
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpression{Operator: AND, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseExpression(valueFilter bool) (Expression, error) {
+	t := p.peek()
+	switch t.typ {
+	case tokenOpenParen:
+		return p.parseGroup(false, valueFilter)
+	case tokenWord:
+		if strings.EqualFold(t.value, "not") && p.tokens[p.pos+1].typ == tokenOpenParen {
+			p.next()
+			return p.parseGroup(true, valueFilter)
+		}
+		return p.parseAttributeExpression(valueFilter)
+	default:
+		return nil, p.unexpected(t)
+	}
+}
+
+func (p *parser) parseGroup(not, valueFilter bool) (Expression, error) {
+	if _, err := p.expect(tokenOpenParen); err != nil {
+		return nil, err
+	}
+	expr, err := p.parseFilter(valueFilter)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokenCloseParen); err != nil {
+		return nil, err
+	}
+	return &GroupExpression{Not: not, Expression: expr}, nil
+}
+
+func (p *parser) parseAttributeExpression(valueFilter bool) (Expression, error) {
+	t := p.next()
+	path, err := parseAttributePath(t.value, t.offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().typ == tokenOpenBracket {
+		if valueFilter {
+			return nil, &SyntaxError{Offset: p.peek().offset, Msg: "nested value filters are not allowed"}
+		}
+		return p.parseValuePath(path)
+	}
+
+	op, err := p.expect(tokenWord)
+	if err != nil {
+		return nil, err
+	}
+	operator := CompareOperator(strings.ToLower(op.value))
+	switch operator {
+	case "pr":
+		return &PresentExpression{AttributePath: path}, nil
+	case EQ, NE, CO, SW, EW, GT, LT, GE, LE:
+		value, err := p.parseCompareValue()
+		if err != nil {
+			return nil, err
+		}
+		return &ComparisonExpression{
+			AttributePath: path,
+			Operator:      operator,
+			CompareValue:  value,
+		}, nil
+	default:
+		return nil, &SyntaxError{Offset: op.offset, Msg: fmt.Sprintf("unknown operator %q", op.value)}
+	}
+}
+
+func (p *parser) parseValuePath(path AttributePath) (Expression, error) {
+	if path.SubAttribute != "" {
+		return nil, &SyntaxError{Offset: p.peek().offset, Msg: "value filters can not be applied to sub attributes"}
+	}
+	p.next() // "["
+	filter, err := p.parseFilter(true)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokenCloseBracket); err != nil {
+		return nil, err
+	}
+	return &ValuePathExpression{AttributePath: path, Filter: filter}, nil
+}
+
+func (p *parser) parseCompareValue() (interface{}, error) {
+	t := p.next()
+	switch t.typ {
+	case tokenString:
+		var s string
+		if err := json.Unmarshal([]byte(t.value), &s); err != nil {
+			return nil, &SyntaxError{Offset: t.offset, Msg: fmt.Sprintf("invalid string %s", t.value)}
+		}
+		return s, nil
+	case tokenWord:
+		switch strings.ToLower(t.value) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		// Numbers follow the JSON grammar, which strconv does not enforce (e.g. "+5" or "05").
+		if json.Valid([]byte(t.value)) {
+			if i, err := strconv.Atoi(t.value); err == nil {
+				return i, nil
+			}
+			if f, err := strconv.ParseFloat(t.value, 64); err == nil {
+				return f, nil
+			}
+		}
+		return nil, &SyntaxError{Offset: t.offset, Msg: fmt.Sprintf("invalid compare value %q", t.value)}
+	default:
+		return nil, &SyntaxError{Offset: t.offset, Msg: fmt.Sprintf("expected compare value, got %s", t)}
+	}
+}
+
+// parseAttributePath parses an attribute path, the offset is used to position errors.
+func parseAttributePath(s string, offset int) (AttributePath, error) {
+	var path AttributePath
+	name := s
+	if i := strings.LastIndex(s, ":"); i != -1 {
+		path.URI = s[:i]
+		name = s[i+1:]
+		offset += i + 1
+		if path.URI == "" {
+			return path, &SyntaxError{Offset: offset - 1, Msg: "empty schema URI"}
+		}
+	}
+
+	parts := strings.Split(name, ".")
+	if len(parts) > 2 {
+		return path, &SyntaxError{Offset: offset, Msg: fmt.Sprintf("invalid attribute path %q", s)}
+	}
+	if !validAttributeName(parts[0]) {
+		return path, &SyntaxError{Offset: offset, Msg: fmt.Sprintf("invalid attribute name %q", parts[0])}
+	}
+	path.AttributeName = parts[0]
+	if len(parts) == 2 {
+		if !validAttributeName(parts[1]) {
+			return path, &SyntaxError{Offset: offset + len(parts[0]) + 1, Msg: fmt.Sprintf("invalid attribute name %q", parts[1])}
+		}
+		path.SubAttribute = parts[1]
+	}
+	return path, nil
+}
+
+// validAttributeName checks whether the name is an ALPHA followed by "-", "_", DIGIT or ALPHA characters.
+// Names starting with "$" are allowed for attributes like "$ref".
+func validAttributeName(name string) bool {
+	name = strings.TrimPrefix(name, "$")
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i != 0 && ('0' <= c && c <= '9' || c == '-' || c == '_'):
+		default:
+			return false
+		}
+	}
+	return true
+}
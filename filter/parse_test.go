@@ -0,0 +1,172 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func ExampleParse() {
+	expr, _ := Parse(`userName Eq "bjensen" AND emails[type eq "work" and value co "@example.com"]`)
+	fmt.Println(expr)
+
+	_, err := Parse(`userName eq`)
+	fmt.Println(err)
+
+	// Output:
+	// userName eq "bjensen" and emails[type eq "work" and value co "@example.com"]
+	// invalid filter at offset 11: expected compare value, got end of filter
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		filter string
+		expr   Expression
+	}{
+		{
+			filter: `userName eq "bjensen"`,
+			expr: &ComparisonExpression{
+				AttributePath: AttributePath{AttributeName: "userName"},
+				Operator:      EQ,
+				CompareValue:  "bjensen",
+			},
+		},
+		{
+			filter: `urn:ietf:params:scim:schemas:core:2.0:User:name.familyName co "O'Malley"`,
+			expr: &ComparisonExpression{
+				AttributePath: AttributePath{
+					URI:           "urn:ietf:params:scim:schemas:core:2.0:User",
+					AttributeName: "name",
+					SubAttribute:  "familyName",
+				},
+				Operator:     CO,
+				CompareValue: "O'Malley",
+			},
+		},
+		{
+			filter: `title pr or userType eq "Employee" and active eq true`,
+			expr: &LogicalExpression{
+				Operator: OR,
+				Left:     &PresentExpression{AttributePath: AttributePath{AttributeName: "title"}},
+				Right: &LogicalExpression{
+					Operator: AND,
+					Left: &ComparisonExpression{
+						AttributePath: AttributePath{AttributeName: "userType"},
+						Operator:      EQ,
+						CompareValue:  "Employee",
+					},
+					Right: &ComparisonExpression{
+						AttributePath: AttributePath{AttributeName: "active"},
+						Operator:      EQ,
+						CompareValue:  true,
+					},
+				},
+			},
+		},
+		{
+			filter: `not (age gt 21.5) and x.y le -3`,
+			expr: &LogicalExpression{
+				Operator: AND,
+				Left: &GroupExpression{
+					Not: true,
+					Expression: &ComparisonExpression{
+						AttributePath: AttributePath{AttributeName: "age"},
+						Operator:      GT,
+						CompareValue:  21.5,
+					},
+				},
+				Right: &ComparisonExpression{
+					AttributePath: AttributePath{AttributeName: "x", SubAttribute: "y"},
+					Operator:      LE,
+					CompareValue:  -3,
+				},
+			},
+		},
+		{
+			filter: `members[$ref ne null]`,
+			expr: &ValuePathExpression{
+				AttributePath: AttributePath{AttributeName: "members"},
+				Filter: &ComparisonExpression{
+					AttributePath: AttributePath{AttributeName: "$ref"},
+					Operator:      NE,
+					CompareValue:  nil,
+				},
+			},
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			expr, err := Parse(test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(expr, test.expr) {
+				t.Errorf("expected %s, got %s", test.expr, expr)
+			}
+		})
+	}
+}
+
+func TestParse_invalid(t *testing.T) {
+	for _, test := range []struct {
+		filter string
+		offset int
+	}{
+		{filter: ``, offset: 0},
+		{filter: `userName`, offset: 8},
+		{filter: `userName xx "x"`, offset: 9},
+		{filter: `userName eq "x`, offset: 12},
+		{filter: `userName eq bjensen`, offset: 12},
+		{filter: `(userName pr`, offset: 12},
+		{filter: `userName pr)`, offset: 11},
+		{filter: `1userName pr`, offset: 0},
+		{filter: `name.givenName.x pr`, offset: 0},
+		{filter: `emails[type[value pr]]`, offset: 11},
+		{filter: `name.givenName[value pr]`, offset: 14},
+		{filter: `userName eq "x" & title pr`, offset: 16},
+		{filter: `age eq +5`, offset: 7},
+		{filter: `age eq 05`, offset: 7},
+		{filter: `age eq .5`, offset: 7},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			_, err := Parse(test.filter)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected syntax error, got %v", err)
+			}
+			if syntaxErr.Offset != test.offset {
+				t.Errorf("expected offset %d, got %d: %s", test.offset, syntaxErr.Offset, err)
+			}
+		})
+	}
+}
+
+func TestExpression_String(t *testing.T) {
+	for filter, canonical := range map[string]string{
+		`userName EQ  "bjensen"`:                    `userName eq "bjensen"`,
+		`(a pr Or b pr) and c pr`:                   `(a pr or b pr) and c pr`,
+		`NOT(a eq 1e3)`:                             `not (a eq 1000.0)`,
+		`a sw "<A\"\\>"`:                            `a sw "<A\"\\>"`,
+		`emails[type eq "work" and not (value pr)]`: `emails[type eq "work" and not (value pr)]`,
+	} {
+		expr, err := Parse(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := expr.String(); s != canonical {
+			t.Errorf("expected %s, got %s", canonical, s)
+		}
+		if again, _ := Parse(canonical); !reflect.DeepEqual(expr, again) {
+			t.Errorf("canonical form %s does not parse into the same expression", canonical)
+		}
+	}
+
+	expr := &LogicalExpression{
+		Operator: AND,
+		Left:     &LogicalExpression{Operator: OR, Left: &PresentExpression{AttributePath{AttributeName: "a"}}, Right: &PresentExpression{AttributePath{AttributeName: "b"}}},
+		Right:    &PresentExpression{AttributePath{AttributeName: "c"}},
+	}
+	if s := expr.String(); s != `(a pr or b pr) and c pr` {
+		t.Errorf("unexpected canonical form: %s", s)
+	}
+}
@@ -0,0 +1,77 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Path is an attribute path as used by PATCH operations (RFC 7644 §3.5.2).
+// e.g. "name.givenName", `emails[type eq "work"].value` or
+// "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value"
+type Path struct {
+	// AttributePath is the attribute the path refers to. If there is a value filter,
+	// the sub attribute is the one that follows the filter.
+	AttributePath AttributePath
+	// ValueFilter selects the values of a multi valued attribute, nil if not present.
+	ValueFilter Expression
+}
+
+func (p Path) String() string {
+	if p.ValueFilter == nil {
+		return p.AttributePath.String()
+	}
+	attr := p.AttributePath
+	attr.SubAttribute = ""
+	s := fmt.Sprintf("%s[%s]", attr, p.ValueFilter)
+	if p.AttributePath.SubAttribute != "" {
+		s += "." + p.AttributePath.SubAttribute
+	}
+	return s
+}
+
+// ParsePath parses the given attribute path.
+func ParsePath(path string) (Path, error) {
+	tokens, err := lex(path)
+	if err != nil {
+		return Path{}, err
+	}
+	p := parser{tokens: tokens}
+
+	t, err := p.expect(tokenWord)
+	if err != nil {
+		return Path{}, err
+	}
+	attrPath, err := parseAttributePath(t.value, t.offset)
+	if err != nil {
+		return Path{}, err
+	}
+	result := Path{AttributePath: attrPath}
+
+	if p.peek().typ == tokenOpenBracket {
+		if attrPath.SubAttribute != "" {
+			return Path{}, &SyntaxError{Offset: p.peek().offset, Msg: "value filters can not be applied to sub attributes"}
+		}
+		p.next() // "["
+		if result.ValueFilter, err = p.parseFilter(true); err != nil {
+			return Path{}, err
+		}
+		end, err := p.expect(tokenCloseBracket)
+		if err != nil {
+			return Path{}, err
+		}
+
+		if t := p.peek(); t.typ == tokenWord && t.offset == end.offset+1 {
+			p.next()
+			sub := strings.TrimPrefix(t.value, ".")
+			if len(sub) == len(t.value) || !validAttributeName(sub) {
+				return Path{}, &SyntaxError{Offset: t.offset, Msg: fmt.Sprintf("invalid sub attribute %q", t.value)}
+			}
+			result.AttributePath.SubAttribute = sub
+		}
+	}
+
+	if t := p.peek(); t.typ != tokenEOF {
+		return Path{}, p.unexpected(t)
+	}
+	return result, nil
+}
@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	for _, test := range []struct {
+		path     string
+		expected Path
+	}{
+		{
+			path:     "userName",
+			expected: Path{AttributePath: AttributePath{AttributeName: "userName"}},
+		},
+		{
+			path:     "name.givenName",
+			expected: Path{AttributePath: AttributePath{AttributeName: "name", SubAttribute: "givenName"}},
+		},
+		{
+			path: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value",
+			expected: Path{AttributePath: AttributePath{
+				URI:           "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+				AttributeName: "manager",
+				SubAttribute:  "value",
+			}},
+		},
+		{
+			path: `members[value eq "2819c223"]`,
+			expected: Path{
+				AttributePath: AttributePath{AttributeName: "members"},
+				ValueFilter: &ComparisonExpression{
+					AttributePath: AttributePath{AttributeName: "value"},
+					Operator:      EQ,
+					CompareValue:  "2819c223",
+				},
+			},
+		},
+		{
+			path: `emails[type eq "work"].value`,
+			expected: Path{
+				AttributePath: AttributePath{AttributeName: "emails", SubAttribute: "value"},
+				ValueFilter: &ComparisonExpression{
+					AttributePath: AttributePath{AttributeName: "type"},
+					Operator:      EQ,
+					CompareValue:  "work",
+				},
+			},
+		},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			path, err := ParsePath(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(path, test.expected) {
+				t.Errorf("expected %s, got %s", test.expected, path)
+			}
+			if s := path.String(); s != test.path {
+				t.Errorf("expected %s, got %s", test.path, s)
+			}
+		})
+	}
+}
+
+func TestParsePath_invalid(t *testing.T) {
+	for _, path := range []string{
+		``,
+		`userName eq "x"`,
+		`name.givenName[value pr]`,
+		`emails[type eq "work"]value`,
+		`emails[type eq "work"] .value`,
+		`emails[type eq "work"].value.x`,
+		`emails[type eq "work"`,
+		`emails[type[value pr]]`,
+	} {
+		t.Run(path, func(t *testing.T) {
+			if _, err := ParsePath(path); err == nil {
+				t.Error("error expected, got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package sqlfilter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/memsql/scimtools/schema"
+)
+
+// Dialect generates the SQL expressions needed to query JSON columns.
+type Dialect interface {
+	// Elements returns a table expression that yields one row for each element of the JSON array in the column,
+	// together with the expression that refers to the element within that row.
+	Elements(column, alias string) (table, element string)
+	// Extract returns an expression that extracts the value with the given key of a JSON object as the given type.
+	// If the key is empty, the JSON value itself is converted.
+	// Booleans are extracted as the strings "true" and "false".
+	Extract(json, key string, typ schema.Type) string
+	// DateTime returns an expression that converts the extracted (RFC 3339) dateTime string into a datetime in UTC,
+	// so that values with different offsets or fractional seconds are compared correctly.
+	DateTime(value string) string
+}
+
+// MySQL generates JSON expressions for MySQL 8.
+var MySQL Dialect = mysql{}
+
+// SingleStore generates JSON expressions for SingleStore (MemSQL).
+var SingleStore Dialect = singleStore{}
+
+type mysql struct{}
+
+func (mysql) Elements(column, alias string) (string, string) {
+	return fmt.Sprintf("JSON_TABLE(%s, '$[*]' COLUMNS (value JSON PATH '$')) AS %s", column, alias), alias + ".value"
+}
+
+func (mysql) Extract(json, key string, typ schema.Type) string {
+	path := "$"
+	if key != "" {
+		path = fmt.Sprintf(`$."%s"`, key)
+	}
+	switch typ {
+	case schema.IntegerType, schema.DecimalType:
+		return fmt.Sprintf("JSON_EXTRACT(%s, '%s')", json, path)
+	default:
+		return fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '%s'))", json, path)
+	}
+}
+
+// DateTime replaces the "Z" designator by an offset, MySQL converts offsets to the session time zone (expected to be
+// UTC) when casting.
+func (mysql) DateTime(value string) string {
+	return fmt.Sprintf("CAST(REPLACE(%s, 'Z', '+00:00') AS DATETIME(6))", value)
+}
+
+type singleStore struct{}
+
+func (singleStore) Elements(column, alias string) (string, string) {
+	return fmt.Sprintf("TABLE(JSON_TO_ARRAY(%s)) AS %s", column, alias), alias + ".table_col"
+}
+
+func (singleStore) Extract(json, key string, typ schema.Type) string {
+	var f string
+	switch typ {
+	case schema.IntegerType:
+		f = "JSON_EXTRACT_BIGINT"
+	case schema.DecimalType:
+		f = "JSON_EXTRACT_DOUBLE"
+	default:
+		f = "JSON_EXTRACT_STRING"
+	}
+	if key == "" {
+		return fmt.Sprintf("%s(%s)", f, json)
+	}
+	return fmt.Sprintf("%s(%s, '%s')", f, json, key)
+}
+
+// DateTime splits the value into its local date time and its offset, SingleStore ignores offsets when casting, and
+// converts the local date time from that offset to UTC.
+func (singleStore) DateTime(value string) string {
+	return fmt.Sprintf("CONVERT_TZ("+
+		"CAST(REPLACE(IF(RIGHT(%[1]s, 1) = 'Z', LEFT(%[1]s, LENGTH(%[1]s) - 1), LEFT(%[1]s, LENGTH(%[1]s) - 6)), 'T', ' ') AS DATETIME(6)), "+
+		"IF(RIGHT(%[1]s, 1) = 'Z', '+00:00', RIGHT(%[1]s, 6)), '+00:00')", value)
+}
+
+// validKey checks whether the key can be embedded within a JSON path, keys are attribute names defined in a schema.
+func validKey(key string) bool {
+	return key != "" && strings.IndexFunc(key, func(r rune) bool {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return false
+		case r == '-', r == '_', r == '$':
+			return false
+		default:
+			return true
+		}
+	}) == -1
+}
@@ -0,0 +1,471 @@
+// Package sqlfilter translates SCIM filters into parameterized SQL WHERE clauses.
+package sqlfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/memsql/scimtools/attributes"
+	"github.com/memsql/scimtools/filter"
+	"github.com/memsql/scimtools/schema"
+	"github.com/memsql/scimtools/validate"
+)
+
+// Column describes where the value of an attribute is stored.
+type Column struct {
+	// Expression is the SQL expression that refers to the column, e.g. "u.user_name".
+	// It is embedded as is, so it should never contain user input.
+	Expression string
+	// JSON indicates that the column holds the value as JSON:
+	// an array for multi valued attributes and an object for complex attributes.
+	JSON bool
+}
+
+// Translator translates filters into SQL based on a mapping of attribute paths to columns.
+type Translator struct {
+	s       schema.ReferenceSchema
+	ext     []schema.ReferenceSchema
+	columns map[string]Column
+	dialect Dialect
+}
+
+// New returns a new Translator for resources described by the given schema and extensions.
+// The columns are keyed by attribute path, e.g. "userName", "name.givenName", "emails" or
+// "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber" (case insensitive).
+// Sub attributes of complex attributes stored in a JSON column do not need to be mapped separately.
+func New(s schema.ReferenceSchema, columns map[string]Column, ext ...schema.ReferenceSchema) *Translator {
+	c := make(map[string]Column, len(columns))
+	for k, v := range columns {
+		c[strings.ToLower(k)] = v
+	}
+	return &Translator{
+		s:       s,
+		ext:     ext,
+		columns: c,
+		dialect: MySQL,
+	}
+}
+
+// Dialect sets the dialect used to query JSON columns, defaults to MySQL.
+func (t *Translator) Dialect(d Dialect) *Translator {
+	t.dialect = d
+	return t
+}
+
+// Translate converts the filter into a WHERE clause (without the "WHERE" keyword) and its arguments.
+// Compare values are always passed as arguments, never embedded in the query.
+// Returns an "invalidFilter" validate.Error if an attribute is unknown, not mapped or can not be compared.
+func (t *Translator) Translate(expr filter.Expression) (string, []interface{}, error) {
+	tr := translation{Translator: t}
+	where, err := tr.translate(scope{}, expr)
+	if err != nil {
+		return "", nil, err
+	}
+	return where, tr.args, nil
+}
+
+type translation struct {
+	*Translator
+	args    []interface{}
+	aliases int
+}
+
+// scope defines how attribute paths are resolved, within a value path the attributes are relative to the parent.
+type scope struct {
+	// attrs are the sub attributes of the parent, nil at the top level.
+	attrs []*schema.Attribute
+	// prefix is the attribute path of the parent, used to find mapped sub attribute columns.
+	prefix string
+	// object is an expression of a JSON object that holds the sub attributes, empty if not stored as JSON.
+	object string
+}
+
+// operand describes the value(s) of an attribute within SQL.
+type operand struct {
+	attribute *schema.Attribute
+	// expr is the expression of the value, it refers to the rows of table if set.
+	expr string
+	// table yields a row for every value of a multi valued attribute, empty for single valued attributes.
+	table string
+	// json indicates that the value was extracted from JSON.
+	json bool
+}
+
+func (t *translation) translate(sc scope, expr filter.Expression) (string, error) {
+	switch expr := expr.(type) {
+	case *filter.LogicalExpression:
+		left, err := t.translate(sc, expr.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := t.translate(sc, expr.Right)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s %s %s)", left, strings.ToUpper(string(expr.Operator)), right), nil
+	case *filter.GroupExpression:
+		where, err := t.translate(sc, expr.Expression)
+		if err != nil {
+			return "", err
+		}
+		if expr.Not {
+			return fmt.Sprintf("NOT (%s)", where), nil
+		}
+		return where, nil
+	case *filter.PresentExpression:
+		return t.present(sc, expr.AttributePath)
+	case *filter.ComparisonExpression:
+		return t.compare(sc, expr)
+	case *filter.ValuePathExpression:
+		return t.valuePath(sc, expr)
+	default:
+		return "", fmt.Errorf("unknown expression: %T", expr)
+	}
+}
+
+func (t *translation) present(sc scope, path filter.AttributePath) (string, error) {
+	attribute, sub, key, err := t.lookup(sc, path)
+	if err != nil {
+		return "", err
+	}
+
+	if sub == nil {
+		if sc.object != "" {
+			return fmt.Sprintf("%s IS NOT NULL", t.dialect.Extract(sc.object, attribute.Name, attribute.Type)), nil
+		}
+		c, ok := t.columns[key]
+		if !ok {
+			return "", notMapped(path)
+		}
+		if c.JSON && attribute.MultiValued {
+			return fmt.Sprintf("JSON_LENGTH(%s) > 0", c.Expression), nil
+		}
+		return present(operand{attribute: attribute, expr: c.Expression, json: c.JSON}), nil
+	}
+
+	o, err := t.operand(sc, path, attribute, sub, key)
+	if err != nil {
+		return "", err
+	}
+	return t.exists(o, present(o)), nil
+}
+
+// present checks whether the operand has a value, empty strings in (non JSON) columns are considered absent.
+func present(o operand) string {
+	if !o.json && stringType(o.attribute.Type) {
+		return fmt.Sprintf("(%s IS NOT NULL AND %s <> '')", o.expr, o.expr)
+	}
+	return fmt.Sprintf("%s IS NOT NULL", o.expr)
+}
+
+func (t *translation) compare(sc scope, expr *filter.ComparisonExpression) (string, error) {
+	attribute, sub, key, err := t.lookup(sc, expr.AttributePath)
+	if err != nil {
+		return "", err
+	}
+	if sub == nil && attribute.Type == schema.ComplexType {
+		// Complex attributes are compared by their "value" sub attribute.
+		if sub = attributes.FindAttribute("value", attribute.SubAttributes); sub == nil {
+			return "", invalid(expr.AttributePath, "complex attribute can not be compared")
+		}
+	}
+	o, err := t.operand(sc, expr.AttributePath, attribute, sub, key)
+	if err != nil {
+		return "", err
+	}
+
+	if expr.CompareValue == nil {
+		switch expr.Operator {
+		case filter.EQ:
+			if o.table == "" {
+				return fmt.Sprintf("%s IS NULL", o.expr), nil
+			}
+			return "NOT " + t.exists(o, fmt.Sprintf("%s IS NOT NULL", o.expr)), nil
+		case filter.NE:
+			return t.exists(o, fmt.Sprintf("%s IS NOT NULL", o.expr)), nil
+		default:
+			return "", invalid(expr.AttributePath, fmt.Sprintf("can not compare with null using %q", expr.Operator))
+		}
+	}
+
+	op := expr.Operator
+	if op == filter.NE {
+		op = filter.EQ
+	}
+	condition, err := t.condition(expr.AttributePath, o, op, expr.CompareValue)
+	if err != nil {
+		return "", err
+	}
+	if expr.Operator != filter.NE {
+		return t.exists(o, condition), nil
+	}
+	if o.table != "" {
+		return "NOT " + t.exists(o, condition), nil
+	}
+	return fmt.Sprintf("(%s IS NULL OR NOT %s)", o.expr, condition), nil
+}
+
+func (t *translation) valuePath(sc scope, expr *filter.ValuePathExpression) (string, error) {
+	if sc.attrs != nil {
+		return "", invalid(expr.AttributePath, "nested value paths are not supported")
+	}
+	attribute, _, key, err := t.lookup(sc, expr.AttributePath)
+	if err != nil {
+		return "", err
+	}
+	if attribute.Type != schema.ComplexType {
+		return "", invalid(expr.AttributePath, "value paths are only supported for complex attributes")
+	}
+
+	c, ok := t.columns[key]
+	switch {
+	case ok && c.JSON && attribute.MultiValued:
+		table, element := t.elements(c.Expression)
+		where, err := t.translate(scope{attrs: attribute.SubAttributes, object: element}, expr.Filter)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s)", table, where), nil
+	case ok && c.JSON:
+		return t.translate(scope{attrs: attribute.SubAttributes, object: c.Expression}, expr.Filter)
+	case !attribute.MultiValued:
+		return t.translate(scope{attrs: attribute.SubAttributes, prefix: key + "."}, expr.Filter)
+	default:
+		return "", notMapped(expr.AttributePath)
+	}
+}
+
+// lookup finds the definition of the attribute (and sub attribute) and the key of the attribute within the columns.
+func (t *translation) lookup(sc scope, path filter.AttributePath) (attribute, sub *schema.Attribute, key string, err error) {
+	if sc.attrs != nil {
+		if path.URI != "" || path.SubAttribute != "" {
+			return nil, nil, "", invalid(path, "invalid attribute path within value path")
+		}
+		if attribute = attributes.FindAttribute(path.AttributeName, sc.attrs); attribute == nil {
+			return nil, nil, "", invalid(path, "unknown attribute")
+		}
+		return attribute, nil, sc.prefix + strings.ToLower(attribute.Name), nil
+	}
+
+	if path.URI == "" || strings.EqualFold(path.URI, t.s.ID) {
+		attribute = attributes.FindAttribute(path.AttributeName, t.s.Attributes)
+		if attribute == nil {
+			attribute = attributes.FindAttribute(path.AttributeName, schema.CoreAttributes)
+		}
+		if attribute != nil {
+			key = strings.ToLower(attribute.Name)
+		}
+	}
+	for _, e := range t.ext {
+		if attribute != nil {
+			break
+		}
+		if path.URI != "" && !strings.EqualFold(path.URI, e.ID) {
+			continue
+		}
+		if attribute = attributes.FindAttribute(path.AttributeName, e.Attributes); attribute != nil {
+			key = strings.ToLower(e.ID + ":" + attribute.Name)
+		}
+	}
+	if attribute == nil {
+		return nil, nil, "", invalid(path, "unknown attribute")
+	}
+
+	if path.SubAttribute != "" {
+		if sub = attributes.FindAttribute(path.SubAttribute, attribute.SubAttributes); sub == nil || attribute.Type != schema.ComplexType {
+			return nil, nil, "", invalid(path, "unknown attribute")
+		}
+	}
+	return attribute, sub, key, nil
+}
+
+// operand resolves the expression of the (sub) attribute.
+func (t *translation) operand(sc scope, path filter.AttributePath, attribute, sub *schema.Attribute, key string) (operand, error) {
+	if sc.object != "" {
+		if attribute.MultiValued || !validKey(attribute.Name) {
+			return operand{}, invalid(path, "attribute can not be queried")
+		}
+		return operand{
+			attribute: attribute,
+			expr:      t.dialect.Extract(sc.object, attribute.Name, attribute.Type),
+			json:      true,
+		}, nil
+	}
+
+	if sub != nil {
+		// The sub attribute has its own column.
+		if c, ok := t.columns[key+"."+strings.ToLower(sub.Name)]; ok {
+			return t.columnOperand(sub, c, attribute.MultiValued || sub.MultiValued), nil
+		}
+		c, ok := t.columns[key]
+		if !ok || !c.JSON {
+			return operand{}, notMapped(path)
+		}
+		if sub.MultiValued || !validKey(sub.Name) {
+			return operand{}, invalid(path, "attribute can not be queried")
+		}
+		if !attribute.MultiValued {
+			return operand{
+				attribute: sub,
+				expr:      t.dialect.Extract(c.Expression, sub.Name, sub.Type),
+				json:      true,
+			}, nil
+		}
+		table, element := t.elements(c.Expression)
+		return operand{
+			attribute: sub,
+			expr:      t.dialect.Extract(element, sub.Name, sub.Type),
+			table:     table,
+			json:      true,
+		}, nil
+	}
+
+	c, ok := t.columns[key]
+	if !ok {
+		return operand{}, notMapped(path)
+	}
+	return t.columnOperand(attribute, c, attribute.MultiValued), nil
+}
+
+func (t *translation) columnOperand(attribute *schema.Attribute, c Column, multiValued bool) operand {
+	if !c.JSON {
+		return operand{attribute: attribute, expr: c.Expression}
+	}
+	if !multiValued {
+		return operand{
+			attribute: attribute,
+			expr:      t.dialect.Extract(c.Expression, "", attribute.Type),
+			json:      true,
+		}
+	}
+	table, element := t.elements(c.Expression)
+	return operand{
+		attribute: attribute,
+		expr:      t.dialect.Extract(element, "", attribute.Type),
+		table:     table,
+		json:      true,
+	}
+}
+
+// condition creates the comparison of the operand with the value, the value is added to the arguments.
+func (t *translation) condition(path filter.AttributePath, o operand, op filter.CompareOperator, value interface{}) (string, error) {
+	attribute := o.attribute
+	switch attribute.Type {
+	case schema.BooleanType:
+		b, ok := value.(bool)
+		if !ok {
+			return "", invalid(path, fmt.Sprintf("can not compare boolean with %v", value))
+		}
+		if op != filter.EQ {
+			return "", invalid(path, fmt.Sprintf("operator %q is not supported for booleans", op))
+		}
+		if o.json {
+			return t.arg(o.expr, "=", strconv.FormatBool(b)), nil
+		}
+		return t.arg(o.expr, "=", b), nil
+	case schema.IntegerType, schema.DecimalType:
+		switch value.(type) {
+		case int, float64:
+		default:
+			return "", invalid(path, fmt.Sprintf("can not compare number with %v", value))
+		}
+		switch op {
+		case filter.CO, filter.SW, filter.EW:
+			return "", invalid(path, fmt.Sprintf("operator %q is not supported for numbers", op))
+		}
+		return t.arg(o.expr, sqlOperator(op), value), nil
+	case schema.ComplexType:
+		return "", invalid(path, "complex attribute can not be compared")
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return "", invalid(path, fmt.Sprintf("can not compare %s with %v", attribute.Type, value))
+	}
+	if attribute.Type == schema.DateTimeType && op != filter.CO && op != filter.SW && op != filter.EW {
+		d, err := time.Parse(time.RFC3339Nano, str)
+		if err != nil {
+			return "", invalid(path, fmt.Sprintf("invalid dateTime %q", str))
+		}
+		if !o.json {
+			return t.arg(o.expr, sqlOperator(op), d.UTC()), nil
+		}
+		// JSON values are strings, which can not be compared lexically.
+		return t.arg(t.dialect.DateTime(o.expr), sqlOperator(op), d.UTC()), nil
+	}
+
+	expr := o.expr
+	if !attribute.CaseExact {
+		expr, str = fmt.Sprintf("LOWER(%s)", expr), strings.ToLower(str)
+	}
+	switch op {
+	case filter.CO:
+		return t.arg(expr, "LIKE", "%"+escapeLike(str)+"%"), nil
+	case filter.SW:
+		return t.arg(expr, "LIKE", escapeLike(str)+"%"), nil
+	case filter.EW:
+		return t.arg(expr, "LIKE", "%"+escapeLike(str)), nil
+	}
+	return t.arg(expr, sqlOperator(op), str), nil
+}
+
+// exists wraps the condition in an EXISTS sub query if the operand is multi valued.
+func (t *translation) exists(o operand, condition string) string {
+	if o.table == "" {
+		return condition
+	}
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s)", o.table, condition)
+}
+
+func (t *translation) elements(column string) (string, string) {
+	t.aliases++
+	return t.dialect.Elements(column, fmt.Sprintf("e%d", t.aliases))
+}
+
+func (t *translation) arg(expr, op string, value interface{}) string {
+	t.args = append(t.args, value)
+	return fmt.Sprintf("%s %s ?", expr, op)
+}
+
+func sqlOperator(op filter.CompareOperator) string {
+	switch op {
+	case filter.GT:
+		return ">"
+	case filter.GE:
+		return ">="
+	case filter.LT:
+		return "<"
+	case filter.LE:
+		return "<="
+	default:
+		return "="
+	}
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func stringType(typ schema.Type) bool {
+	switch typ {
+	case schema.StringType, schema.ReferenceType, schema.BinaryType, "":
+		return true
+	default:
+		return false
+	}
+}
+
+func invalid(path filter.AttributePath, detail string) error {
+	return &validate.Error{
+		ScimType: validate.InvalidFilter,
+		Path:     path.String(),
+		Detail:   detail,
+	}
+}
+
+func notMapped(path filter.AttributePath) error {
+	return invalid(path, "attribute is not mapped to a column")
+}
@@ -0,0 +1,221 @@
+package sqlfilter_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/memsql/scimtools/filter"
+	"github.com/memsql/scimtools/filter/sqlfilter"
+	"github.com/memsql/scimtools/schema"
+	"github.com/memsql/scimtools/validate"
+)
+
+var (
+	userSchema = schema.ReferenceSchema{
+		ID: "urn:ietf:params:scim:schemas:core:2.0:User",
+		Attributes: []*schema.Attribute{
+			{Name: "userName", Type: schema.StringType},
+			{Name: "nickName", Type: schema.StringType, CaseExact: true},
+			{Name: "title", Type: schema.StringType},
+			{Name: "active", Type: schema.BooleanType},
+			{Name: "loginCount", Type: schema.IntegerType},
+			{Name: "lastLogin", Type: schema.DateTimeType},
+			{
+				Name: "name",
+				Type: schema.ComplexType,
+				SubAttributes: []*schema.Attribute{
+					{Name: "givenName", Type: schema.StringType},
+					{Name: "familyName", Type: schema.StringType},
+				},
+			},
+			{
+				Name:        "emails",
+				Type:        schema.ComplexType,
+				MultiValued: true,
+				SubAttributes: []*schema.Attribute{
+					{Name: "value", Type: schema.StringType},
+					{Name: "type", Type: schema.StringType},
+					{Name: "primary", Type: schema.BooleanType},
+				},
+			},
+			{Name: "roles", Type: schema.StringType, MultiValued: true},
+			{
+				Name: "meta",
+				Type: schema.ComplexType,
+				SubAttributes: []*schema.Attribute{
+					{Name: "lastModified", Type: schema.DateTimeType},
+				},
+			},
+		},
+	}
+	enterpriseSchema = schema.ReferenceSchema{
+		ID: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+		Attributes: []*schema.Attribute{
+			{Name: "employeeNumber", Type: schema.StringType},
+		},
+	}
+	columns = map[string]sqlfilter.Column{
+		"id":              {Expression: "u.id"},
+		"userName":        {Expression: "u.user_name"},
+		"nickName":        {Expression: "u.nick_name"},
+		"active":          {Expression: "u.active"},
+		"loginCount":      {Expression: "u.login_count"},
+		"lastLogin":       {Expression: "u.last_login"},
+		"name.givenName":  {Expression: "u.given_name"},
+		"name.familyName": {Expression: "u.family_name"},
+		"emails":          {Expression: "u.emails", JSON: true},
+		"roles":           {Expression: "u.roles", JSON: true},
+		"meta":            {Expression: "u.meta", JSON: true},
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber": {Expression: "e.employee_number"},
+	}
+)
+
+func ExampleTranslator_Translate() {
+	expr, _ := filter.Parse(`userName eq "bjensen" and emails[type eq "work" and value co "@example.com"]`)
+	where, args, _ := sqlfilter.New(userSchema, columns).Translate(expr)
+	fmt.Println(where)
+	fmt.Println(args)
+
+	// Output:
+	// (LOWER(u.user_name) = ? AND EXISTS (SELECT 1 FROM JSON_TABLE(u.emails, '$[*]' COLUMNS (value JSON PATH '$')) AS e1 WHERE (LOWER(JSON_UNQUOTE(JSON_EXTRACT(e1.value, '$."type"'))) = ? AND LOWER(JSON_UNQUOTE(JSON_EXTRACT(e1.value, '$."value"'))) LIKE ?)))
+	// [bjensen work %@example.com%]
+}
+
+func TestTranslate(t *testing.T) {
+	lastLogin := time.Date(2011, 5, 13, 2, 42, 34, 0, time.UTC)
+	for _, test := range []struct {
+		filter string
+		where  string
+		args   []interface{}
+	}{
+		{
+			filter: `nickName eq "Babs" or nickName ne "O'Malley"`,
+			where:  `(u.nick_name = ? OR (u.nick_name IS NULL OR NOT u.nick_name = ?))`,
+			args:   []interface{}{"Babs", "O'Malley"},
+		},
+		{
+			filter: `userName sw "b_j%" and not (active eq true)`,
+			where:  `(LOWER(u.user_name) LIKE ? AND NOT (u.active = ?))`,
+			args:   []interface{}{`b\_j\%%`, true},
+		},
+		{
+			filter: `loginCount gt 10 and lastLogin le "2011-05-13T04:42:34+02:00"`,
+			where:  `(u.login_count > ? AND u.last_login <= ?)`,
+			args:   []interface{}{10, lastLogin},
+		},
+		{
+			filter: `meta.lastModified gt "2011-05-13T06:42:34.5+02:00" and meta.lastModified sw "2011"`,
+			where:  `(CAST(REPLACE(JSON_UNQUOTE(JSON_EXTRACT(u.meta, '$."lastModified"')), 'Z', '+00:00') AS DATETIME(6)) > ? AND LOWER(JSON_UNQUOTE(JSON_EXTRACT(u.meta, '$."lastModified"'))) LIKE ?)`,
+			args:   []interface{}{time.Date(2011, 5, 13, 4, 42, 34, 5e8, time.UTC), "2011%"},
+		},
+		{
+			filter: `name.givenName pr and name[familyName ew "sen"]`,
+			where:  `((u.given_name IS NOT NULL AND u.given_name <> '') AND LOWER(u.family_name) LIKE ?)`,
+			args:   []interface{}{"%sen"},
+		},
+		{
+			filter: `emails.type eq "work"`,
+			where:  `EXISTS (SELECT 1 FROM JSON_TABLE(u.emails, '$[*]' COLUMNS (value JSON PATH '$')) AS e1 WHERE LOWER(JSON_UNQUOTE(JSON_EXTRACT(e1.value, '$."type"'))) = ?)`,
+			args:   []interface{}{"work"},
+		},
+		{
+			filter: `emails ne "bjensen@example.com"`,
+			where:  `NOT EXISTS (SELECT 1 FROM JSON_TABLE(u.emails, '$[*]' COLUMNS (value JSON PATH '$')) AS e1 WHERE LOWER(JSON_UNQUOTE(JSON_EXTRACT(e1.value, '$."value"'))) = ?)`,
+			args:   []interface{}{"bjensen@example.com"},
+		},
+		{
+			filter: `emails pr and emails[primary eq true]`,
+			where:  `(JSON_LENGTH(u.emails) > 0 AND EXISTS (SELECT 1 FROM JSON_TABLE(u.emails, '$[*]' COLUMNS (value JSON PATH '$')) AS e1 WHERE JSON_UNQUOTE(JSON_EXTRACT(e1.value, '$."primary"')) = ?))`,
+			args:   []interface{}{"true"},
+		},
+		{
+			filter: `roles eq "admin"`,
+			where:  `EXISTS (SELECT 1 FROM JSON_TABLE(u.roles, '$[*]' COLUMNS (value JSON PATH '$')) AS e1 WHERE LOWER(JSON_UNQUOTE(JSON_EXTRACT(e1.value, '$'))) = ?)`,
+			args:   []interface{}{"admin"},
+		},
+		{
+			filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "701984" and id eq null`,
+			where:  `(LOWER(e.employee_number) = ? AND u.id IS NULL)`,
+			args:   []interface{}{"701984"},
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			expr, err := filter.Parse(test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			where, args, err := sqlfilter.New(userSchema, columns, enterpriseSchema).Translate(expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if where != test.where {
+				t.Errorf("expected\n%s\ngot\n%s", test.where, where)
+			}
+			if !reflect.DeepEqual(args, test.args) {
+				t.Errorf("expected %#v, got %#v", test.args, args)
+			}
+		})
+	}
+}
+
+func TestTranslate_singleStore(t *testing.T) {
+	expr, err := filter.Parse(`emails[type eq "work" and primary eq true] or roles eq "admin"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	where, _, err := sqlfilter.New(userSchema, columns).Dialect(sqlfilter.SingleStore).Translate(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = `(EXISTS (SELECT 1 FROM TABLE(JSON_TO_ARRAY(u.emails)) AS e1 WHERE (LOWER(JSON_EXTRACT_STRING(e1.table_col, 'type')) = ? AND JSON_EXTRACT_STRING(e1.table_col, 'primary') = ?)) OR EXISTS (SELECT 1 FROM TABLE(JSON_TO_ARRAY(u.roles)) AS e2 WHERE LOWER(JSON_EXTRACT_STRING(e2.table_col)) = ?))`
+	if where != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, where)
+	}
+}
+
+func TestTranslate_singleStoreDateTime(t *testing.T) {
+	expr, err := filter.Parse(`meta.lastModified gt "2011-05-13T06:42:34.5+02:00"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	where, _, err := sqlfilter.New(userSchema, columns).Dialect(sqlfilter.SingleStore).Translate(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		value    = `JSON_EXTRACT_STRING(u.meta, 'lastModified')`
+		expected = `CONVERT_TZ(CAST(REPLACE(IF(RIGHT(` + value + `, 1) = 'Z', LEFT(` + value + `, LENGTH(` + value + `) - 1), LEFT(` + value + `, LENGTH(` + value + `) - 6)), 'T', ' ') AS DATETIME(6)), IF(RIGHT(` + value + `, 1) = 'Z', '+00:00', RIGHT(` + value + `, 6)), '+00:00') > ?`
+	)
+	if where != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, where)
+	}
+}
+
+func TestTranslate_invalid(t *testing.T) {
+	for _, filterString := range []string{
+		`title eq "Tour Guide"`,
+		`unknown eq "x"`,
+		`urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager eq "x"`,
+		`active gt true`,
+		`loginCount sw 1`,
+		`userName eq 1`,
+		`lastLogin gt "yesterday"`,
+		`emails[type eq "work" and display eq "x"]`,
+		`userName[value pr]`,
+	} {
+		t.Run(filterString, func(t *testing.T) {
+			expr, err := filter.Parse(filterString)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, err = sqlfilter.New(userSchema, columns, enterpriseSchema).Translate(expr)
+			var validationErr *validate.Error
+			if !errors.As(err, &validationErr) || validationErr.ScimType != validate.InvalidFilter {
+				t.Errorf("expected invalidFilter error, got %v", err)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/gofuzz"
+	"github.com/memsql/scimtools/filter"
 	"github.com/memsql/scimtools/schema"
 )
 
@@ -55,30 +56,35 @@ func (f *Fuzzer) Fuzz() map[string]interface{} {
 // NeverEmpty makes sure that all passed attribute names are never empty during fuzzing.
 // Setting a complex attribute on never empty will also make their sub attributes never empty.
 // i.e. "displayName", "name.givenName" or "emails.value"
+// Panics if a name is not a valid attribute path.
 func (f *Fuzzer) NeverEmpty(names ...string) *Fuzzer {
-	for _, attribute := range f.schema.Attributes {
-		for _, name := range names {
-			neverEmpty(name, attribute)
+	for _, name := range names {
+		path, err := filter.ParsePath(name)
+		if err != nil {
+			panic(fmt.Sprintf("invalid attribute path %q: %s", name, err))
+		}
+		for _, attribute := range f.schema.Attributes {
+			neverEmpty(path.AttributePath, attribute)
 		}
 	}
 	return f
 }
 
-func neverEmpty(name string, attribute *schema.Attribute) {
-	n := strings.SplitN(name, ".", 2)
-	if strings.EqualFold(n[0], attribute.Name) {
-		if len(n) > 1 && attribute.Type == schema.ComplexType {
-			for _, subAttribute := range attribute.SubAttributes {
-				neverEmpty(n[1], subAttribute)
-			}
-		} else {
-			attribute.Required = true
-			if attribute.Type == schema.ComplexType {
-				attribute.ForEachAttribute(func(attribute *schema.Attribute) {
-					attribute.Required = true
-				})
-			}
+func neverEmpty(path filter.AttributePath, attribute *schema.Attribute) {
+	if !strings.EqualFold(path.AttributeName, attribute.Name) {
+		return
+	}
+	if path.SubAttribute != "" && attribute.Type == schema.ComplexType {
+		for _, subAttribute := range attribute.SubAttributes {
+			neverEmpty(filter.AttributePath{AttributeName: path.SubAttribute}, subAttribute)
 		}
+		return
+	}
+	attribute.Required = true
+	if attribute.Type == schema.ComplexType {
+		attribute.ForEachAttribute(func(attribute *schema.Attribute) {
+			attribute.Required = true
+		})
 	}
 }
 
@@ -0,0 +1,171 @@
+package generate
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/memsql/scimtools/schema"
+)
+
+// JSONSchemaDialect is the URI of the JSON Schema dialect of the generated schemas.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a (subset of a) JSON Schema, as used by JSON Schema draft 2020-12 and OpenAPI 3.1.
+type JSONSchema struct {
+	Schema          string                 `json:"$schema,omitempty"`
+	ID              string                 `json:"$id,omitempty"`
+	Ref             string                 `json:"$ref,omitempty"`
+	Title           string                 `json:"title,omitempty"`
+	Description     string                 `json:"description,omitempty"`
+	Type            string                 `json:"type,omitempty"`
+	Format          string                 `json:"format,omitempty"`
+	ContentEncoding string                 `json:"contentEncoding,omitempty"`
+	Enum            []string               `json:"enum,omitempty"`
+	Items           *JSONSchema            `json:"items,omitempty"`
+	Properties      map[string]*JSONSchema `json:"properties,omitempty"`
+	Required        []string               `json:"required,omitempty"`
+	ReadOnly        bool                   `json:"readOnly,omitempty"`
+	WriteOnly       bool                   `json:"writeOnly,omitempty"`
+	Defs            map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+// OpenAPIComponents is the components object of an OpenAPI 3.1 document.
+type OpenAPIComponents struct {
+	Schemas map[string]*JSONSchema `json:"schemas"`
+}
+
+// NewJSONSchema converts the reference schema into a JSON Schema (draft 2020-12) document. Extensions are defined
+// within "$defs", by name, and referenced by the property named after the URN of the extension.
+// The common attributes (schemas, id, externalId and meta) are added if the schema does not define them.
+func NewJSONSchema(s schema.ReferenceSchema, extensions ...schema.ReferenceSchema) (*JSONSchema, error) {
+	if err := checkNames(s, extensions); err != nil {
+		return nil, err
+	}
+	root := resourceSchema(s, extensions, "#/$defs/")
+	root.Schema = JSONSchemaDialect
+	root.ID = s.ID
+	if len(extensions) != 0 {
+		root.Defs = make(map[string]*JSONSchema)
+		for _, e := range extensions {
+			root.Defs[e.Name] = extensionSchema(e)
+		}
+	}
+	return root, nil
+}
+
+// NewOpenAPIComponents converts the reference schema and its extensions into OpenAPI 3.1 component schemas, named
+// after the schemas. The resource references the extensions by the property named after the URN of the extension.
+func NewOpenAPIComponents(s schema.ReferenceSchema, extensions ...schema.ReferenceSchema) (*OpenAPIComponents, error) {
+	if err := checkNames(s, extensions); err != nil {
+		return nil, err
+	}
+	components := &OpenAPIComponents{
+		Schemas: map[string]*JSONSchema{
+			s.Name: resourceSchema(s, extensions, "#/components/schemas/"),
+		},
+	}
+	for _, e := range extensions {
+		if _, ok := components.Schemas[e.Name]; ok {
+			return nil, errors.New("extension has the same name as another schema")
+		}
+		components.Schemas[e.Name] = extensionSchema(e)
+	}
+	return components, nil
+}
+
+func checkNames(s schema.ReferenceSchema, extensions []schema.ReferenceSchema) error {
+	if s.Name == "" {
+		return errors.New("schema does not have a name")
+	}
+	for _, extension := range extensions {
+		if extension.ID == "" || extension.Name == "" {
+			return errors.New("extension does not have a name/id")
+		}
+	}
+	return nil
+}
+
+// resourceSchema returns the schema of the resource, the extensions are referenced with the given prefix.
+func resourceSchema(s schema.ReferenceSchema, extensions []schema.ReferenceSchema, ref string) *JSONSchema {
+	attrs := append([]*schema.Attribute{}, s.Attributes...)
+	for _, attribute := range schema.CoreAttributes {
+		if !hasAttribute(attrs, attribute.Name) {
+			attrs = append(attrs, attribute)
+		}
+	}
+	root := objectSchema(attrs)
+	root.Title = s.Name
+	root.Description = s.Description
+	for _, e := range extensions {
+		root.Properties[e.ID] = &JSONSchema{Ref: ref + e.Name}
+	}
+	return root
+}
+
+func extensionSchema(e schema.ReferenceSchema) *JSONSchema {
+	root := objectSchema(e.Attributes)
+	root.Title = e.Name
+	root.Description = e.Description
+	return root
+}
+
+func objectSchema(attrs []*schema.Attribute) *JSONSchema {
+	object := &JSONSchema{
+		Type:       "object",
+		Properties: make(map[string]*JSONSchema),
+	}
+	for _, attribute := range attrs {
+		object.Properties[attribute.Name] = attributeSchema(attribute)
+		if attribute.Required {
+			object.Required = append(object.Required, attribute.Name)
+		}
+	}
+	return object
+}
+
+func attributeSchema(attribute *schema.Attribute) *JSONSchema {
+	value := &JSONSchema{}
+	switch attribute.Type {
+	case schema.BooleanType:
+		value.Type = "boolean"
+	case schema.DecimalType:
+		value.Type = "number"
+	case schema.IntegerType:
+		value.Type = "integer"
+	case schema.DateTimeType:
+		value.Type = "string"
+		value.Format = "date-time"
+	case schema.BinaryType:
+		value.Type = "string"
+		value.ContentEncoding = "base64"
+	case schema.ReferenceType:
+		value.Type = "string"
+		value.Format = "uri-reference"
+	case schema.ComplexType:
+		value = objectSchema(attribute.SubAttributes)
+	default:
+		value.Type = "string"
+	}
+	value.Enum = attribute.CanonicalValues
+
+	s := value
+	if attribute.MultiValued {
+		s = &JSONSchema{
+			Type:  "array",
+			Items: value,
+		}
+	}
+	s.Description = attribute.Description
+	s.ReadOnly = attribute.Mutability == schema.ReadOnly
+	s.WriteOnly = attribute.Mutability == schema.WriteOnly
+	return s
+}
+
+func hasAttribute(attrs []*schema.Attribute, name string) bool {
+	for _, attribute := range attrs {
+		if strings.EqualFold(attribute.Name, name) {
+			return true
+		}
+	}
+	return false
+}
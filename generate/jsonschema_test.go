@@ -0,0 +1,121 @@
+package generate_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/memsql/scimtools/generate"
+	"github.com/memsql/scimtools/schema"
+)
+
+func ExampleNewJSONSchema() {
+	s, _ := generate.NewJSONSchema(schema.ReferenceSchema{
+		ID:   "urn:example:params:scim:schemas:core:2.0:Device",
+		Name: "Device",
+		Attributes: []*schema.Attribute{
+			{Name: "serial", Type: schema.StringType, Required: true, Mutability: schema.Immutable},
+			{Name: "tags", Type: schema.StringType, MultiValued: true, CanonicalValues: []string{"laptop", "phone"}},
+			{Name: "lastSeen", Type: schema.DateTimeType, Mutability: schema.ReadOnly},
+		},
+	})
+	delete(s.Properties, "meta")
+	delete(s.Properties, "schemas")
+
+	raw, _ := json.MarshalIndent(s, "", "  ")
+	fmt.Println(string(raw))
+
+	// Output:
+	// {
+	//   "$schema": "https://json-schema.org/draft/2020-12/schema",
+	//   "$id": "urn:example:params:scim:schemas:core:2.0:Device",
+	//   "title": "Device",
+	//   "type": "object",
+	//   "properties": {
+	//     "externalId": {
+	//       "description": "A String that is an identifier for the resource as defined by the\nprovisioning client.",
+	//       "type": "string"
+	//     },
+	//     "id": {
+	//       "description": "A unique identifier for a SCIM resource as defined by the service provider.",
+	//       "type": "string",
+	//       "readOnly": true
+	//     },
+	//     "lastSeen": {
+	//       "type": "string",
+	//       "format": "date-time",
+	//       "readOnly": true
+	//     },
+	//     "serial": {
+	//       "type": "string"
+	//     },
+	//     "tags": {
+	//       "type": "array",
+	//       "items": {
+	//         "type": "string",
+	//         "enum": [
+	//           "laptop",
+	//           "phone"
+	//         ]
+	//       }
+	//     }
+	//   },
+	//   "required": [
+	//     "serial",
+	//     "schemas",
+	//     "id"
+	//   ]
+	// }
+}
+
+func ExampleNewOpenAPIComponents() {
+	components, _ := generate.NewOpenAPIComponents(schema.UserSchema, schema.EnterpriseUserSchema)
+	fmt.Println(components.Schemas["User"].Properties[schema.EnterpriseUserSchema.ID].Ref)
+
+	manager := components.Schemas["EnterpriseUser"].Properties["manager"]
+	raw, _ := json.Marshal(manager.Properties["displayName"])
+	fmt.Println(string(raw))
+
+	// Output:
+	// #/components/schemas/EnterpriseUser
+	// {"description":"The displayName of the User's manager.  OPTIONAL and READ-ONLY.","type":"string","readOnly":true}
+}
+
+func TestNewJSONSchema(t *testing.T) {
+	if _, err := generate.NewJSONSchema(schema.ReferenceSchema{}); err == nil {
+		t.Error("error expected, got none")
+	}
+	if _, err := generate.NewOpenAPIComponents(schema.UserSchema, schema.ReferenceSchema{ID: "urn:example"}); err == nil {
+		t.Error("error expected, got none")
+	}
+
+	s, err := generate.NewJSONSchema(schema.UserSchema, schema.EnterpriseUserSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref := s.Properties[schema.EnterpriseUserSchema.ID].Ref; ref != "#/$defs/EnterpriseUser" {
+		t.Errorf("unexpected extension reference %q", ref)
+	}
+	if _, ok := s.Defs["EnterpriseUser"]; !ok {
+		t.Error("expected the extension to be defined")
+	}
+
+	for name, expected := range map[string]generate.JSONSchema{
+		"userName": {Type: "string"},
+		"active":   {Type: "boolean"},
+		"password": {Type: "string", WriteOnly: true},
+		"photos":   {Type: "array"},
+		"groups":   {Type: "array", ReadOnly: true},
+	} {
+		p := s.Properties[name]
+		if p == nil || p.Type != expected.Type || p.ReadOnly != expected.ReadOnly || p.WriteOnly != expected.WriteOnly {
+			t.Errorf("%s: unexpected schema %+v", name, p)
+		}
+	}
+	if value := s.Properties["photos"].Items.Properties["value"]; value.Format != "uri-reference" {
+		t.Errorf("unexpected photo value %+v", value)
+	}
+	if value := s.Properties["x509Certificates"].Items.Properties["value"]; value.ContentEncoding != "base64" {
+		t.Errorf("unexpected certificate value %+v", value)
+	}
+}
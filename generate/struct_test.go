@@ -2,6 +2,8 @@ package generate_test
 
 import (
 	"fmt"
+	"go/parser"
+	"go/token"
 	"testing"
 
 	"github.com/memsql/scimtools/schema"
@@ -15,6 +17,22 @@ func TestGenerateStruct(t *testing.T) {
 	}
 }
 
+func TestGenerateStruct_builtin(t *testing.T) {
+	for _, s := range [][]schema.ReferenceSchema{
+		{schema.UserSchema, schema.EnterpriseUserSchema},
+		{schema.GroupSchema},
+	} {
+		g, err := generate.NewStructGenerator(s[0], s[1:]...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src := "package scim\n\n" + g.Generate().String()
+		if _, err := parser.ParseFile(token.NewFileSet(), "", src, 0); err != nil {
+			t.Errorf("%s: %s", s[0].Name, err)
+		}
+	}
+}
+
 func ExampleStructGenerator_AddTags() {
 	g, _ := generate.NewStructGenerator(schema.ReferenceSchema{
 		Name:        "User",
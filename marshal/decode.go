@@ -1,31 +1,61 @@
 package marshal
 
 import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/muir/reflectutils"
+	"github.com/memsql/scimtools/attributes"
+	"github.com/memsql/scimtools/validate"
 )
 
 var (
-	ummarshalluuidType = reflect.TypeOf((*IDUnMarshaler)(nil)).Elem()
-	unmarshalerType    = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
-	mapStringAnyType   = reflect.TypeOf(map[string]interface{}{})
-	anySliceType       = reflect.TypeOf([]interface{}{})
+	ummarshalluuidType  = reflect.TypeOf((*IDUnMarshaler)(nil)).Elem()
+	unmarshalerType     = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	mapStringAnyType    = reflect.TypeOf(map[string]interface{}{})
+	anySliceType        = reflect.TypeOf([]interface{}{})
 )
 
+// Unmarshal fills the struct the value points to with the given resource, it is the inverse of Marshal and supports
+// the same tags. Attribute names are matched case insensitive, an error is returned if multiple keys of the resource
+// match the same attribute. Fields of attributes that are not present (or nil) are left untouched, pointers are only allocated
+// if the attribute they point to is present.
+//
+// Complex multi valued attributes of fields that are not a slice (e.g. `scim:"emails/value,mV"`) are matched by
+// position: the first of those fields with the same name receives the first value, etc. Use the "index=" option to
+// select the value explicitly.
+//
+// Attributes that can not be decoded do not stop the decoding of the other attributes, all of them are reported as
+// validate.Errors with the full path of the attribute (e.g. "emails[1].primary").
 func Unmarshal(data map[string]interface{}, value interface{}) error {
+	return unmarshal(data, value, false)
+}
+
+// UnmarshalStrict is like Unmarshal, but also reports the attributes of the resource that are not mapped to any field
+// with the invalidSyntax type. The schemas attribute is always accepted, attributes decoded into maps or interfaces
+// can contain any sub attribute.
+func UnmarshalStrict(data map[string]interface{}, value interface{}) error {
+	return unmarshal(data, value, true)
+}
+
+func unmarshal(data map[string]interface{}, value interface{}, strict bool) error {
 	v := reflect.ValueOf(value)
 	if v.Kind() != reflect.Ptr || v.IsNil() {
 		return errors.New("value is invalid")
 	}
 
-	t := v.Type()
-	if t.Implements(unmarshalerType) {
-		if v.Kind() == reflect.Ptr && v.IsNil() {
-			return errors.New("ptr is nil")
-		}
+	if v.Type().Implements(unmarshalerType) {
 		m, ok := v.Interface().(Unmarshaler)
 		if !ok {
 			return errors.New("value does not implement marshaler")
@@ -33,150 +63,696 @@ func Unmarshal(data map[string]interface{}, value interface{}) error {
 		return m.UnmarshalSCIM(data)
 	}
 
-	if t.Kind() == reflect.Ptr {
-		v = v.Elem()
-		t = v.Type()
+	v = v.Elem()
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	d := decoder{
+		strict:     strict,
+		extensions: make(map[string]bool),
 	}
+	d.unmarshalStruct("", data, v)
+	return d.err()
+}
 
-	var err error
-	reflectutils.WalkStructElements(t, func(sf reflect.StructField) bool {
-		tag := parseTags(sf)
+// Unmarshaler is the interface implemented by types that can unmarshal a SCIM description of themselves.
+type Unmarshaler interface {
+	UnmarshalSCIM(map[string]interface{}) error
+}
 
-		if sf.Anonymous {
-			return true
-		}
+type IDUnMarshaler interface {
+	UnmarshalSCIMUUID(interface{}) error
+}
 
-		if v := v.FieldByIndex(sf.Index); v.CanAddr() && v.CanSet() {
-			name := lowerFirstRune(tag.name)
+// decoder keeps track of the errors of a single call to Unmarshal.
+type decoder struct {
+	// strict reports attributes that are not mapped to any field.
+	strict bool
+	// extensions contains the paths of the decoded extensions, their sub attributes are separated by a colon.
+	extensions map[string]bool
+	errs       validate.Errors
+}
 
-			if fV, ok := data[name]; ok {
-				if fV == nil {
-					return false
-				}
-				s := reflect.ValueOf(fV)
-				switch s.Kind() {
-				case reflect.Array, reflect.Slice:
-					if t, ok := fV.([]map[string]interface{}); ok {
-						field := reflect.MakeSlice(v.Type(), len(t), len(t))
-						for i, v := range t {
-							if reflect.ValueOf(v).Kind() == reflect.Map {
-								t := toDefaultMap(v)
-								typ := field.Index(i).Type()
-								element := reflect.New(typ)
-								initializeStruct(typ, element.Elem())
-								if e := Unmarshal(t, element.Interface()); e != nil {
-									err = e
-								}
-								field.Index(i).Set(element.Elem())
-							} else {
-								field.Index(i).Set(reflect.ValueOf(v))
-							}
-						}
-
-						v.Set(field)
-
-					} else {
-						t := toDefaultSlice(fV)
-
-						// if v.kind not match the value's kind, then skip
-						if v.Kind() != reflect.Slice {
-							break
-						}
-						field := reflect.MakeSlice(v.Type(), len(t), len(t))
-						for i, v := range t {
-							switch reflect.ValueOf(v).Kind() {
-							case reflect.Map:
-								t := toDefaultMap(v)
-								typ := field.Index(i).Type()
-								element := reflect.New(typ)
-								initializeStruct(typ, element.Elem())
-								if e := Unmarshal(t, element.Interface()); e != nil {
-									err = e
-								}
-								field.Index(i).Set(element.Elem())
-							default:
-								field.Index(i).Set(reflect.ValueOf(v))
-							}
-						}
-						v.Set(field)
+func (d *decoder) errorf(typ validate.ScimType, path, format string, args ...interface{}) {
+	d.errs = append(d.errs, &validate.Error{
+		ScimType: typ,
+		Path:     path,
+		Detail:   fmt.Sprintf(format, args...),
+	})
+}
 
-					}
+// err returns the collected errors ordered by path, nil if there are none.
+func (d *decoder) err() error {
+	if len(d.errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(d.errs, func(i, j int) bool {
+		return d.errs[i].Path < d.errs[j].Path
+	})
+	return d.errs
+}
 
-					return false
-				case reflect.Map:
-					t := toDefaultMap(fV)
-					field := reflect.New(v.Type())
-					initializeStruct(v.Type(), field.Elem())
-					if e := Unmarshal(t, field.Interface()); e != nil {
-						err = e
+// unmarshalStruct fills the fields of the struct with the attributes of the resource, the prefix is used to report
+// errors of nested structs.
+func (d *decoder) unmarshalStruct(prefix string, data map[string]interface{}, v reflect.Value) {
+	// positions keeps track of the values of complex multi valued attributes that are already used, by name.
+	positions := make(map[string]int)
+	// names contains the (lower case) attributes that are mapped to a field, subs the mapped sub attributes of
+	// attributes that are only partially mapped (e.g. `scim:"name/givenName"`). Only used in strict mode.
+	names := make(map[string]bool)
+	subs := make(map[string]map[string]bool)
+
+	var decodeFields func(fields []fieldPlan)
+	decodeFields = func(fields []fieldPlan) {
+		for _, f := range fields {
+			if f.embedded {
+				decodeFields(f.fields)
+				continue
+			}
+			if !f.exported {
+				continue
+			}
+
+			tag := f.tag
+			if d.strict {
+				name := strings.ToLower(tag.name)
+				if tag.sub == nil {
+					names[name] = true
+				} else {
+					if subs[name] == nil {
+						subs[name] = make(map[string]bool)
 					}
-					v.Set(field.Elem())
-					return false
+					subs[name][strings.ToLower(tag.sub.name)] = true
 				}
+			}
+			if prefix == "" && tag.extension() {
+				d.extensions[tag.name] = true
+				d.decodeField(prefix, d.extension(data, tag.name), v, f.index, tag, -1)
+				continue
+			}
 
-				if s.Kind() != v.Kind() {
-					err = fmt.Errorf(
-						"types of %q do not match: got %s, want %s",
-						name, s.Type(), v.Type(),
-					)
-				}
+			position := -1
+			if tag.sub != nil && tag.multiValued && !f.list {
+				key := tag.name + "/" + tag.sub.name
+				position = positions[key]
+				positions[key]++
+			}
+			d.decodeField(prefix, data, v, f.index, tag, position)
+		}
+	}
+	decodeFields(planOf(v.Type()).fields)
 
-				if s.Type() != v.Type() {
-					// special handle with uuid type
-					if v.CanAddr() && v.Addr().Type().Implements(ummarshalluuidType) {
-						m, ok := v.Addr().Interface().(IDUnMarshaler)
-						if !ok {
-							err = errors.New("value does not implement IDUnMarshaler")
-						}
-						err = m.UnmarshalSCIMUUID(fV)
-					} else {
-						v.Set(reflect.ValueOf(toType(fV, v.Type())))
-					}
+	if !d.strict {
+		return
+	}
+	for _, key := range sortedKeys(data) {
+		name := strings.ToLower(key)
+		switch {
+		case names[name]:
+		case prefix == "" && (name == "schemas" || d.flattened(name)):
+		case subs[name] != nil:
+			d.checkSubAttributes(prefix+key, data[key], subs[name])
+		default:
+			d.errorf(validate.InvalidSyntax, prefix+key, "unknown attribute")
+		}
+	}
+}
 
-				} else {
-					v.Set(s)
-				}
+// extension returns the resource that contains the attributes of the extension with the given URN, both the ones of
+// the extension attribute and the flattened ones that are prefixed with the URN (e.g. "<urn>:employeeNumber" or
+// "<urn>:manager.value").
+func (d *decoder) extension(data map[string]interface{}, urn string) map[string]interface{} {
+	prefix := strings.ToLower(urn) + ":"
+	var flattened []string
+	for _, key := range sortedKeys(data) {
+		if strings.HasPrefix(strings.ToLower(key), prefix) {
+			flattened = append(flattened, key)
+		}
+	}
+	if len(flattened) == 0 {
+		return data
+	}
+
+	raw, ok := d.lookup(urn, data, urn)
+	if !ok {
+		return nil
+	}
+	m, ok := toMap(raw)
+	if raw != nil && !ok {
+		// Extensions that are not complex are reported while decoding.
+		return data
+	}
+	extension := copyMap(m)
+	for _, key := range flattened {
+		name, target := key[len(prefix):], extension
+		if i := strings.Index(name, "."); i != -1 {
+			parent := attributes.ExistingKey(name[:i], target)
+			m, ok := toMap(target[parent])
+			if target[parent] != nil && !ok {
+				d.errorf(validate.InvalidSyntax, key, "%s is not a complex attribute", parent)
+				continue
+			}
+			m = copyMap(m)
+			target[parent], target, name = m, m, name[i+1:]
+		}
+		if existing := attributes.ExistingKey(name, target); target[existing] != nil {
+			d.errorf(validate.InvalidSyntax, key, "duplicate attribute")
+			continue
+		}
+		target[name] = data[key]
+	}
+	return map[string]interface{}{urn: extension}
+}
+
+// flattened returns whether the (lower case) name is a flattened attribute of a decoded extension.
+func (d *decoder) flattened(name string) bool {
+	for urn := range d.extensions {
+		if strings.HasPrefix(name, strings.ToLower(urn)+":") {
+			return true
+		}
+	}
+	return false
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// checkSubAttributes reports the sub attributes of the (multi valued) complex attribute that are not mapped.
+func (d *decoder) checkSubAttributes(path string, raw interface{}, subs map[string]bool) {
+	check := func(prefix string, element interface{}) {
+		m, ok := toMap(element)
+		if !ok {
+			// Values that are not complex are reported while decoding.
+			return
+		}
+		for _, key := range sortedKeys(m) {
+			if !subs[strings.ToLower(key)] {
+				d.errorf(validate.InvalidSyntax, prefix+"."+key, "unknown attribute")
+			}
+		}
+	}
+	if raw == nil {
+		return
+	}
+	if _, ok := toMap(raw); ok {
+		check(path, raw)
+		return
+	}
+	for i, element := range toSlice(raw) {
+		check(fmt.Sprintf("%s[%d]", path, i), element)
+	}
+}
+
+// decodeField decodes the attribute of the tag into the field with the given index, if the attribute is present.
+// The position selects the value of complex multi valued attributes, -1 if the field is a list.
+func (d *decoder) decodeField(prefix string, data map[string]interface{}, v reflect.Value, index []int, tag tag, position int) {
+	path := prefix + tag.name
+	raw, ok := d.lookup(path, data, tag.name)
+	if !ok || raw == nil {
+		return
+	}
+	if tag.sub == nil {
+		field, ok := d.fieldByIndex(path, v, index)
+		if !ok {
+			return
+		}
+		if tag.multiValued {
+			d.decodeMultiValued(path, field, raw)
+			return
+		}
+		d.decodeValue(path, field, raw)
+		return
+	}
+
+	if !tag.multiValued {
+		m, ok := toMap(raw)
+		if !ok {
+			d.errorf(validate.InvalidValue, path, "expected a complex attribute, got %T", raw)
+			return
+		}
+		sub, ok := d.lookup(path+"."+tag.sub.name, m, tag.sub.name)
+		if !ok || sub == nil {
+			return
+		}
+		if field, ok := d.fieldByIndex(path, v, index); ok {
+			d.decodeValue(path+"."+tag.sub.name, field, sub)
+		}
+		return
+	}
+
+	// elements contains the values of the attribute, nil if they do not contain the sub attribute.
+	var elements []map[string]interface{}
+	for i, element := range toSlice(raw) {
+		m, ok := toMap(element)
+		if !ok {
+			d.errorf(validate.InvalidValue, fmt.Sprintf("%s[%d]", path, i), "expected a complex attribute, got %T", element)
+			elements = append(elements, nil)
+			continue
+		}
+		sub, ok := d.lookup(fmt.Sprintf("%s[%d].%s", path, i, tag.sub.name), m, tag.sub.name)
+		if !ok || sub == nil {
+			m = nil
+		}
+		elements = append(elements, m)
+	}
+
+	var (
+		values []map[string]interface{}
+		// offsets contains the positions of the values in the attribute, used to report errors.
+		offsets []int
+	)
+	switch {
+	case position == -1:
+		// Fields that are lists receive every value that contains the sub attribute.
+		for i, element := range elements {
+			if element != nil {
+				values = append(values, element)
+				offsets = append(offsets, i)
+			}
+		}
+	case len(tag.indexes) != 0 && tag.all():
+		for i, element := range elements {
+			if element != nil {
+				values = append(values, element)
+				offsets = append(offsets, i)
+				break
+			}
+		}
+	default:
+		if len(tag.indexes) != 0 {
+			position = tag.indexes[0]
+		}
+		if position < len(elements) && elements[position] != nil {
+			values = append(values, elements[position])
+			offsets = append(offsets, position)
+		}
+	}
+	if len(values) == 0 {
+		return
+	}
+
+	field, ok := d.fieldByIndex(path, v, index)
+	if !ok {
+		return
+	}
+	if position == -1 {
+		field = allocate(field)
+		switch field.Kind() {
+		case reflect.Slice:
+			field.Set(reflect.MakeSlice(field.Type(), len(values), len(values)))
+		case reflect.Array:
+			if len(values) > field.Len() {
+				values = values[:field.Len()]
+			}
+		}
+		for i, value := range values {
+			d.decodeField(fmt.Sprintf("%s[%d].", path, offsets[i]), value, field.Index(i), nil, *tag.sub, -1)
+		}
+		return
+	}
+	d.decodeField(fmt.Sprintf("%s[%d].", path, offsets[0]), values[0], field, nil, *tag.sub, -1)
+}
+
+// lookup returns the value of the attribute with the given name (case insensitive), nil if not present.
+// Reports an error if multiple keys match the name.
+func (d *decoder) lookup(path string, data map[string]interface{}, name string) (interface{}, bool) {
+	var (
+		key   string
+		value interface{}
+		found bool
+	)
+	for k, v := range data {
+		if !strings.EqualFold(k, name) {
+			continue
+		}
+		if found {
+			if key > k {
+				key, k = k, key
 			}
+			d.errorf(validate.InvalidSyntax, path, "duplicate keys: %s and %s", key, k)
+			return nil, false
 		}
+		key, value, found = k, v, true
+	}
+	return value, true
+}
+
+// decodeValue decodes a single value into the field.
+func (d *decoder) decodeValue(path string, field reflect.Value, raw interface{}) {
+	if raw == nil {
+		return
+	}
+	if d.unmarshalScalar(path, field, raw) {
+		return
+	}
+
+	switch field.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		n := len(d.errs)
+		d.decodeValue(path, elem.Elem(), raw)
+		if len(d.errs) == n {
+			field.Set(elem)
+		}
+	case reflect.Interface:
+		value := reflect.ValueOf(raw)
+		if !value.Type().AssignableTo(field.Type()) {
+			d.mismatch(path, value.Type(), field.Type())
+			return
+		}
+		field.Set(value)
+	case reflect.Map:
+		m, ok := toMap(raw)
+		if !ok {
+			d.mismatch(path, reflect.TypeOf(raw), field.Type())
+			return
+		}
+		if field.Type().Key().Kind() != reflect.String {
+			d.errorf(validate.InvalidValue, path, "key of map is not a string")
+			return
+		}
+		values := reflect.MakeMapWithSize(field.Type(), len(m))
+		for _, k := range sortedKeys(m) {
+			value := reflect.New(field.Type().Elem()).Elem()
+			d.decodeValue(path+"."+k, value, m[k])
+			values.SetMapIndex(reflect.ValueOf(k).Convert(field.Type().Key()), value)
+		}
+		field.Set(values)
+	case reflect.Struct:
+		m, ok := toMap(raw)
+		if !ok {
+			d.mismatch(path, reflect.TypeOf(raw), field.Type())
+			return
+		}
+		if field.CanAddr() && field.Addr().Type().Implements(unmarshalerType) {
+			if err := field.Addr().Interface().(Unmarshaler).UnmarshalSCIM(m); err != nil {
+				d.errorf(validate.InvalidValue, path, "%s", err)
+			}
+			return
+		}
+		if d.extensions[path] {
+			d.unmarshalStruct(path+":", m, field)
+			return
+		}
+		d.unmarshalStruct(path+".", m, field)
+	case reflect.Slice, reflect.Array:
+		d.decodeMultiValued(path, field, raw)
+	default:
+		d.convert(path, field, raw)
+	}
+}
+
+// unmarshalScalar decodes the simple attributes that are not decoded based on their kind, the inverse of
+// scalarAttribute. Returns false if the field is not one of them.
+func (d *decoder) unmarshalScalar(path string, field reflect.Value, raw interface{}) bool {
+	if field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface {
 		return false
-	})
+	}
+	if m, ok := implements(field, ummarshalluuidType); ok {
+		if err := m.(IDUnMarshaler).UnmarshalSCIMUUID(raw); err != nil {
+			d.errorf(validate.InvalidValue, path, "%s", err)
+		}
+		return true
+	}
+	if field.Type() == timeType {
+		d.decodeDateTime(path, field, raw)
+		return true
+	}
+	if m, ok := implements(field, textUnmarshalerType); ok {
+		s, ok := raw.(string)
+		if !ok {
+			d.mismatch(path, reflect.TypeOf(raw), field.Type())
+			return true
+		}
+		if err := m.(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			d.errorf(validate.InvalidValue, path, "%s", err)
+		}
+		return true
+	}
+	if m, ok := implements(field, jsonUnmarshalerType); ok {
+		data, err := json.Marshal(raw)
+		if err == nil {
+			err = m.(json.Unmarshaler).UnmarshalJSON(data)
+		}
+		if err != nil {
+			d.errorf(validate.InvalidValue, path, "%s", err)
+		}
+		return true
+	}
+	if isBinary(field.Type()) {
+		d.decodeBinary(path, field, raw)
+		return true
+	}
+	return false
+}
+
+// decodeMultiValued decodes a multi valued attribute into the field. Fields that are not a list receive the first value.
+func (d *decoder) decodeMultiValued(path string, field reflect.Value, raw interface{}) {
+	values := toSlice(raw)
+	if raw != nil && isBinary(reflect.TypeOf(raw)) {
+		values = []interface{}{raw}
+	}
+	if len(values) == 0 {
+		return
+	}
+	if isScalar(field.Type()) {
+		d.decodeValue(path, field, values[0])
+		return
+	}
+
+	switch field.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		n := len(d.errs)
+		d.decodeMultiValued(path, elem.Elem(), raw)
+		if len(d.errs) == n {
+			field.Set(elem)
+		}
+	case reflect.Interface:
+		d.decodeValue(path, field, raw)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, v := range values {
+			d.decodeValue(fmt.Sprintf("%s[%d]", path, i), slice.Index(i), v)
+		}
+		field.Set(slice)
+	case reflect.Array:
+		for i, v := range values {
+			if i == field.Len() {
+				break
+			}
+			d.decodeValue(fmt.Sprintf("%s[%d]", path, i), field.Index(i), v)
+		}
+	default:
+		d.decodeValue(path, field, values[0])
+	}
+}
+
+// convert sets the field to the given simple value, converting it to the type of the field if needed.
+func (d *decoder) convert(path string, field reflect.Value, raw interface{}) {
+	value := reflect.ValueOf(raw)
+	if numeric(field.Kind()) && (numeric(value.Kind()) || value.Type() == numberType) {
+		d.decodeNumber(path, field, raw)
+		return
+	}
+	if value.Type().AssignableTo(field.Type()) {
+		field.Set(value)
+		return
+	}
+	if !compatible(value.Kind(), field.Kind()) || !value.CanConvert(field.Type()) {
+		d.mismatch(path, value.Type(), field.Type())
+		return
+	}
+	field.Set(value.Convert(field.Type()))
+}
+
+// decodeNumber sets the integer or float field to the given number (e.g. a float64 or json.Number). Numbers that do
+// not fit into the field, or fractions for integer fields, are reported.
+func (d *decoder) decodeNumber(path string, field reflect.Value, raw interface{}) {
+	value := reflect.ValueOf(raw)
+	// Fractions in json numbers can need more precision than integers.
+	n := new(big.Float).SetPrec(256)
+	switch {
+	case value.Type() == numberType:
+		if _, ok := n.SetString(value.String()); !ok {
+			d.errorf(validate.InvalidValue, path, "invalid number: %s", value)
+			return
+		}
+	case reflect.Int <= value.Kind() && value.Kind() <= reflect.Int64:
+		n.SetInt64(value.Int())
+	case reflect.Uint <= value.Kind() && value.Kind() <= reflect.Uint64:
+		n.SetUint64(value.Uint())
+	default:
+		f := value.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			if field.Kind() == reflect.Float32 || field.Kind() == reflect.Float64 {
+				field.SetFloat(f)
+				return
+			}
+			d.errorf(validate.InvalidValue, path, "%v is not an integer", f)
+			return
+		}
+		n.SetFloat64(f)
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !n.IsInt() {
+			d.errorf(validate.InvalidValue, path, "%v is not an integer", raw)
+			return
+		}
+		i, accuracy := n.Int64()
+		if accuracy != big.Exact || field.OverflowInt(i) {
+			d.overflow(path, raw, field.Type())
+			return
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !n.IsInt() {
+			d.errorf(validate.InvalidValue, path, "%v is not an integer", raw)
+			return
+		}
+		u, accuracy := n.Uint64()
+		if accuracy != big.Exact || field.OverflowUint(u) {
+			d.overflow(path, raw, field.Type())
+			return
+		}
+		field.SetUint(u)
+	default:
+		f, _ := n.Float64()
+		if number, ok := raw.(json.Number); ok {
+			// Parse the number directly to avoid rounding it twice.
+			f, _ = strconv.ParseFloat(string(number), 64)
+		}
+		if math.IsInf(f, 0) || field.OverflowFloat(f) {
+			d.overflow(path, raw, field.Type())
+			return
+		}
+		field.SetFloat(f)
+	}
+}
+
+// decodeDateTime sets the time field to the given RFC 3339 string.
+func (d *decoder) decodeDateTime(path string, field reflect.Value, raw interface{}) {
+	s, ok := raw.(string)
+	if !ok {
+		d.convert(path, field, raw)
+		return
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
 	if err != nil {
-		return err
+		d.errorf(validate.InvalidValue, path, "invalid dateTime: %q", s)
+		return
 	}
-	return nil
+	field.Set(reflect.ValueOf(t))
 }
 
-// Unmarshaler is the interface implemented by types that can unmarshal a SCIM description of themselves.
-type Unmarshaler interface {
-	UnmarshalSCIM(map[string]interface{}) error
+// decodeBinary sets the byte slice field to the given base64 string.
+func (d *decoder) decodeBinary(path string, field reflect.Value, raw interface{}) {
+	s, ok := raw.(string)
+	if !ok {
+		d.convert(path, field, raw)
+		return
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		d.errorf(validate.InvalidValue, path, "invalid binary: %s", err)
+		return
+	}
+	field.SetBytes(b)
 }
 
-type IDUnMarshaler interface {
-	UnmarshalSCIMUUID(interface{}) error
+func (d *decoder) overflow(path string, raw interface{}, t reflect.Type) {
+	d.errorf(validate.InvalidValue, path, "%v overflows %s", raw, t)
 }
 
-func initializeStruct(t reflect.Type, v reflect.Value) {
-	for i := 0; i < v.NumField(); i++ {
-		f := v.Field(i)
-		ft := t.Field(i)
-		switch ft.Type.Kind() {
-		case reflect.Map:
-			f.Set(reflect.MakeMap(ft.Type))
-		case reflect.Slice:
-			f.Set(reflect.MakeSlice(ft.Type, 0, 0))
-		case reflect.Struct:
-			initializeStruct(ft.Type, f)
-		case reflect.Ptr:
-			fv := reflect.New(ft.Type.Elem())
-			initializeStruct(ft.Type.Elem(), fv.Elem())
-			f.Set(fv)
-		default:
+func (d *decoder) mismatch(path string, got, want reflect.Type) {
+	d.errorf(validate.InvalidValue, path, "types do not match: got %s, want %s", got, want)
+}
+
+// fieldByIndex returns the settable field with the given index, the value itself if the index is empty.
+func (d *decoder) fieldByIndex(path string, v reflect.Value, index []int) (reflect.Value, bool) {
+	if len(index) != 0 {
+		v = v.FieldByIndex(index)
+	}
+	if !v.CanSet() {
+		d.errorf(validate.InvalidValue, path, "can not set field of type %s", v.Type())
+		return reflect.Value{}, false
+	}
+	return v, true
+}
+
+// compatible returns whether values of the first kind can be converted to the second kind without changing their
+// meaning, e.g. integers can not be converted into strings.
+func compatible(from, to reflect.Kind) bool {
+	if numeric(from) && numeric(to) {
+		return true
+	}
+	return from == to
+}
+
+func numeric(k reflect.Kind) bool {
+	return reflect.Int <= k && k <= reflect.Float64 && k != reflect.Uintptr
+}
+
+// allocate returns the value the (possibly nil) pointer points to, allocating it if needed.
+func allocate(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
 		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isList(t reflect.Type) bool {
+	t = derefType(t)
+	return t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+}
+
+// toMap converts any map with string keys into a map[string]interface{}.
+func toMap(value interface{}) (map[string]interface{}, bool) {
+	if m, ok := value.(map[string]interface{}); ok {
+		return m, true
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, false
 	}
+	return toDefaultMap(value), true
+}
+
+// toSlice converts any slice or array into a []interface{}, other values are returned as a slice of one value.
+func toSlice(value interface{}) []interface{} {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []interface{}{value}
+	}
+	if v.Kind() == reflect.Slice {
+		return toDefaultSlice(value)
+	}
+	values := make([]interface{}, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+	return values
 }
 
 func toDefaultMap(m interface{}) map[string]interface{} {
 	if reflect.TypeOf(m) != mapStringAnyType {
+		if v := reflect.ValueOf(m); !v.CanConvert(mapStringAnyType) {
+			values := make(map[string]interface{}, v.Len())
+			for _, k := range v.MapKeys() {
+				values[k.String()] = v.MapIndex(k).Interface()
+			}
+			return values
+		}
 		return toType(m, mapStringAnyType).(map[string]interface{})
 	}
 	return m.(map[string]interface{})
@@ -184,15 +760,30 @@ func toDefaultMap(m interface{}) map[string]interface{} {
 
 func toDefaultSlice(m interface{}) []interface{} {
 	if reflect.TypeOf(m) != anySliceType {
+		if v := reflect.ValueOf(m); !v.CanConvert(anySliceType) {
+			values := make([]interface{}, v.Len())
+			for i := range values {
+				values[i] = v.Index(i).Interface()
+			}
+			return values
+		}
 		return toType(m, anySliceType).([]interface{})
 	}
 	return m.([]interface{})
 }
 
 func toType(i any, t reflect.Type) interface{} {
-	// sqsq canconvert check
 	return reflect.
 		ValueOf(i).
 		Convert(t).
 		Interface()
 }
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,8 +1,18 @@
 package marshal
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	fuzz "github.com/google/gofuzz"
+	"github.com/memsql/scimtools/schema"
+	"github.com/memsql/scimtools/validate"
 )
 
 type testUnmarshalInterface struct {
@@ -172,3 +182,543 @@ func Example() {
 	// map[name:map[familyName:Daenen givenName:Quint] userName:di-wu]
 	// {di-wu {Quint Daenen}}
 }
+
+type roundTripID string
+
+func (id roundTripID) MarshalSCIMUUID() (string, error) {
+	return "id-" + string(id), nil
+}
+
+func (id *roundTripID) UnmarshalSCIMUUID(value interface{}) error {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, "id-") {
+		return fmt.Errorf("invalid id %v", value)
+	}
+	*id = roundTripID(strings.TrimPrefix(s, "id-"))
+	return nil
+}
+
+type roundTripName struct {
+	GivenName  string
+	FamilyName *string
+}
+
+type roundTripEmail struct {
+	Value   string
+	Primary bool
+}
+
+type roundTripEnterprise struct {
+	EmployeeNumber string
+	Manager        *roundTripName
+}
+
+// roundTripCode is encoded as a string by encoding.TextMarshaler.
+type roundTripCode int
+
+func (c roundTripCode) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(int(c))), nil
+}
+
+func (c *roundTripCode) UnmarshalText(text []byte) error {
+	i, err := strconv.Atoi(string(text))
+	*c = roundTripCode(i)
+	return err
+}
+
+// roundTripStatus is encoded as a number by json.Marshaler.
+type roundTripStatus struct {
+	Code int
+}
+
+func (s roundTripStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Code)
+}
+
+func (s *roundTripStatus) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &s.Code)
+}
+
+// roundTripVersion is encoded as a string by encoding.TextMarshaler, which only a pointer implements.
+type roundTripVersion struct {
+	Version string
+}
+
+func (v *roundTripVersion) MarshalText() ([]byte, error) {
+	return []byte("v" + v.Version), nil
+}
+
+func (v *roundTripVersion) UnmarshalText(text []byte) error {
+	v.Version = strings.TrimPrefix(string(text), "v")
+	return nil
+}
+
+type roundTripEmbedded struct {
+	Title  string
+	Locale *string
+}
+
+type roundTripUser struct {
+	roundTripEmbedded
+
+	ID          roundTripID  `scim:"id"`
+	ExternalID  *roundTripID `scim:"externalId"`
+	UserName    string
+	Active      bool `scim:",zero"`
+	Age         *int
+	Score       float32
+	Visits      uint16
+	Name        roundTripName
+	Nickname    *roundTripName
+	GivenName   string           `scim:"alias/givenName"`
+	FamilyName  *string          `scim:"alias/familyName"`
+	Emails      []roundTripEmail `scim:",mV"`
+	Email       *roundTripEmail  `scim:"primaryEmail,mV"`
+	Groups      []string         `scim:",mV"`
+	Entitlement string           `scim:",mV"`
+	Pair        [2]string        `scim:",mV"`
+	Labels      map[string]string
+	Tags        []string        `scim:"tags/value,mV"`
+	WorkPhone   string          `scim:"phoneNumbers/value,mV,index=0"`
+	WorkType    string          `scim:"phoneNumbers/type,mV,index=0"`
+	HomePhone   *string         `scim:"phoneNumbers/value,mV,index=1"`
+	Addresses   []roundTripName `scim:"addresses/name,mV"`
+	Created     time.Time
+	LastLogin   *time.Time
+	Photo       []byte
+	Keys        [][]byte `scim:",mV"`
+	Code        roundTripCode
+	Codes       []roundTripCode `scim:",mV"`
+	Status      *roundTripStatus
+	Version     roundTripVersion
+	Internal    string               `scim:",ignore"`
+	Enterprise  *roundTripEnterprise `scim:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`
+}
+
+func TestUnmarshal_roundTrip(t *testing.T) {
+	f := fuzz.New().NilChance(.3).NumElements(1, 3).Funcs(func(t *time.Time, c fuzz.Continue) {
+		// Decoded date times are in UTC.
+		*t = time.Unix(c.Int63n(1<<33), c.Int63n(1e9)).UTC()
+	}, func(b *[]byte, c fuzz.Continue) {
+		// Binary values are never nil, an empty string decodes into an empty slice.
+		*b = []byte(c.RandString())
+	})
+	for i := 0; i < 1000; i++ {
+		var user roundTripUser
+		f.Fuzz(&user)
+		user.Internal = ""
+		if user.Enterprise != nil && *user.Enterprise == (roundTripEnterprise{}) {
+			// Extensions without attributes are not encoded.
+			user.Enterprise = nil
+		}
+
+		resource, err := Marshal(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded roundTripUser
+		if err := Unmarshal(resource, &decoded); err != nil {
+			t.Fatalf("%v: %v", resource, err)
+		}
+		if !reflect.DeepEqual(user, decoded) {
+			t.Fatalf("round trip failed:\n%#v\n%#v\n%v", user, decoded, resource)
+		}
+
+		again, err := Marshal(&decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(resource, again) {
+			t.Fatalf("round trip failed:\n%v\n%v", resource, again)
+		}
+	}
+}
+
+func TestUnmarshal_pointers(t *testing.T) {
+	var user roundTripUser
+	if err := Unmarshal(map[string]interface{}{
+		"userName": "bjensen",
+		"age":      float64(0),
+	}, &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Age == nil || *user.Age != 0 {
+		t.Errorf("expected a pointer to zero, got %v", user.Age)
+	}
+	if user.ExternalID != nil || user.Nickname != nil || user.FamilyName != nil || user.Email != nil ||
+		user.HomePhone != nil || user.Enterprise != nil || user.Locale != nil {
+		t.Errorf("expected absent attributes to be nil: %+v", user)
+	}
+	if user.Labels != nil || user.Groups != nil {
+		t.Errorf("expected absent attributes to be nil: %+v", user)
+	}
+}
+
+func TestUnmarshal_tags(t *testing.T) {
+	var user roundTripUser
+	if err := Unmarshal(map[string]interface{}{
+		"id":       "id-0",
+		"internal": "secret",
+		"alias":    map[string]interface{}{"givenName": "Babs"},
+		"phoneNumbers": []interface{}{
+			map[string]interface{}{"value": "555-1234", "type": "work"},
+			map[string]interface{}{"value": "555-4321"},
+		},
+		"tags":         []map[string]interface{}{{"value": "a"}, {"other": "b"}, {"value": "c"}},
+		"primaryEmail": []interface{}{map[string]interface{}{"value": "bjensen@example.com"}},
+		"entitlement":  []interface{}{"admin"},
+	}, &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != "0" || user.Internal != "" || user.GivenName != "Babs" {
+		t.Errorf("unexpected user %+v", user)
+	}
+	if user.WorkPhone != "555-1234" || user.WorkType != "work" || user.HomePhone == nil || *user.HomePhone != "555-4321" {
+		t.Errorf("unexpected phone numbers %+v", user)
+	}
+	if !reflect.DeepEqual(user.Tags, []string{"a", "c"}) {
+		t.Errorf("unexpected tags %v", user.Tags)
+	}
+	if user.Email == nil || user.Email.Value != "bjensen@example.com" || user.Entitlement != "admin" {
+		t.Errorf("unexpected multi valued attributes %+v", user)
+	}
+}
+
+func TestUnmarshal_invalid(t *testing.T) {
+	for _, resource := range []map[string]interface{}{
+		{"userName": 1},
+		{"age": "1"},
+		{"id": "0"},
+		{"name": "Barbara"},
+		{"alias": "Babs"},
+		{"phoneNumbers": []interface{}{"555-1234"}},
+		{"groups": []interface{}{1}},
+	} {
+		var user roundTripUser
+		if err := Unmarshal(resource, &user); err == nil {
+			t.Errorf("expected an error for %v", resource)
+		}
+	}
+}
+
+func TestUnmarshal_caseInsensitive(t *testing.T) {
+	var user roundTripUser
+	if err := Unmarshal(map[string]interface{}{
+		"ID":       "id-0",
+		"UserName": "bjensen",
+		"NAME":     map[string]interface{}{"GivenName": "Barbara"},
+		"Emails": []interface{}{
+			map[string]interface{}{"Value": "bjensen@example.com", "PRIMARY": true},
+		},
+		"PhoneNumbers": []interface{}{map[string]interface{}{"VALUE": "555-1234"}},
+		"Tags":         []interface{}{map[string]interface{}{"Value": "a"}},
+		"URN:IETF:PARAMS:SCIM:SCHEMAS:EXTENSION:ENTERPRISE:2.0:USER": map[string]interface{}{
+			"employeeNumber": "701984",
+		},
+	}, &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != "0" || user.UserName != "bjensen" || user.Name.GivenName != "Barbara" {
+		t.Errorf("unexpected user %+v", user)
+	}
+	if len(user.Emails) != 1 || user.Emails[0].Value != "bjensen@example.com" || !user.Emails[0].Primary {
+		t.Errorf("unexpected emails %+v", user.Emails)
+	}
+	if user.WorkPhone != "555-1234" || !reflect.DeepEqual(user.Tags, []string{"a"}) {
+		t.Errorf("unexpected complex multi valued attributes %+v", user)
+	}
+	if user.Enterprise == nil || user.Enterprise.EmployeeNumber != "701984" {
+		t.Errorf("unexpected extension %+v", user.Enterprise)
+	}
+
+	for _, resource := range []map[string]interface{}{
+		{"userName": "bjensen", "UserName": "babs"},
+		{"name": map[string]interface{}{"givenName": "Barbara", "GIVENNAME": "Babs"}},
+		{"tags": []interface{}{map[string]interface{}{"value": "a", "Value": "b"}}},
+	} {
+		var user roundTripUser
+		err := Unmarshal(resource, &user)
+		if err == nil || !strings.Contains(err.Error(), "duplicate keys") {
+			t.Errorf("expected an error for %v, got %v", resource, err)
+		}
+	}
+}
+
+func TestUnmarshal_errors(t *testing.T) {
+	var user roundTripUser
+	err := Unmarshal(map[string]interface{}{
+		"userName": 1,
+		"name":     map[string]interface{}{"givenName": true},
+		"emails": []interface{}{
+			map[string]interface{}{"value": "bjensen@example.com"},
+			map[string]interface{}{"value": "babs@example.com", "primary": "true"},
+		},
+		"phoneNumbers": []interface{}{map[string]interface{}{"value": 5551234}},
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": map[string]interface{}{
+			"employeeNumber": 701984,
+		},
+	}, &user)
+	var errs validate.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	paths := []string{
+		"emails[1].primary",
+		"name.givenName",
+		"phoneNumbers[0].value",
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber",
+		"userName",
+	}
+	if len(errs) != len(paths) {
+		t.Fatalf("expected %d errors, got %v", len(paths), errs)
+	}
+	for i, path := range paths {
+		if errs[i].ScimType != validate.InvalidValue || errs[i].Path != path {
+			t.Errorf("unexpected error %v", errs[i])
+		}
+	}
+	if user.Emails[0].Value != "bjensen@example.com" {
+		t.Errorf("expected valid attributes to be decoded: %+v", user.Emails)
+	}
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	resource := map[string]interface{}{
+		"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+		"userName": "bjensen",
+		"name":     map[string]interface{}{"givenName": "Barbara", "middleName": "Jane"},
+		"labels":   map[string]interface{}{"any": "label"},
+		"emails": []interface{}{
+			map[string]interface{}{"value": "bjensen@example.com", "display": "Babs"},
+		},
+		"phoneNumbers": []interface{}{
+			map[string]interface{}{"value": "555-1234", "type": "work"},
+			map[string]interface{}{"value": "555-5678", "primary": true},
+		},
+		"displayName": "Babs",
+		"internal":    "secret",
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": map[string]interface{}{
+			"employeeNumber": "701984",
+			"costCenter":     "4130",
+		},
+	}
+	var user roundTripUser
+	if err := Unmarshal(resource, &user); err != nil {
+		t.Fatal(err)
+	}
+
+	err := UnmarshalStrict(resource, &user)
+	var errs validate.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	paths := []string{
+		"displayName",
+		"emails[0].display",
+		"internal",
+		"name.middleName",
+		"phoneNumbers[1].primary",
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:costCenter",
+	}
+	if len(errs) != len(paths) {
+		t.Fatalf("expected %d errors, got %v", len(paths), errs)
+	}
+	for i, path := range paths {
+		if errs[i].ScimType != validate.InvalidSyntax || errs[i].Path != path {
+			t.Errorf("unexpected error %v", errs[i])
+		}
+	}
+}
+
+func TestUnmarshal_scalars(t *testing.T) {
+	type scalars struct {
+		Created  time.Time
+		Modified *time.Time
+		Photo    []byte
+		Keys     [][]byte `scim:",mV"`
+		Int8     int8
+		Uint     uint
+		Int      *int
+		Float32  float32
+		Float64  float64
+		Number   json.Number
+	}
+
+	var s scalars
+	if err := Unmarshal(map[string]interface{}{
+		"created":  "2011-05-13T04:42:34Z",
+		"modified": "2011-05-13T04:42:34.5+02:00",
+		"photo":    "/9g=",
+		"keys":     []interface{}{"AQ==", "Ag=="},
+		"int8":     json.Number("-128"),
+		"uint":     float64(1 << 40),
+		"int":      json.Number("1e3"),
+		"float32":  json.Number("0.1"),
+		"float64":  int64(1),
+		"number":   json.Number("12.50"),
+	}, &s); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Created.Equal(time.Date(2011, 5, 13, 4, 42, 34, 0, time.UTC)) ||
+		s.Modified == nil || !s.Modified.Equal(time.Date(2011, 5, 13, 2, 42, 34, 5e8, time.UTC)) {
+		t.Errorf("unexpected date times %v, %v", s.Created, s.Modified)
+	}
+	if !reflect.DeepEqual(s.Photo, []byte{0xff, 0xd8}) || !reflect.DeepEqual(s.Keys, [][]byte{{1}, {2}}) {
+		t.Errorf("unexpected binary values %v, %v", s.Photo, s.Keys)
+	}
+	if s.Int8 != -128 || s.Uint != 1<<40 || s.Int == nil || *s.Int != 1000 || s.Float32 != .1 || s.Float64 != 1 || s.Number != "12.50" {
+		t.Errorf("unexpected numbers %+v", s)
+	}
+
+	resource, err := Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resource["created"] != "2011-05-13T04:42:34Z" || resource["photo"] != "/9g=" || resource["number"] != json.Number("12.50") {
+		t.Errorf("unexpected resource %v", resource)
+	}
+
+	for _, test := range []struct {
+		resource map[string]interface{}
+		detail   string
+	}{
+		{map[string]interface{}{"created": "2011-05-13"}, `invalid dateTime: "2011-05-13"`},
+		{map[string]interface{}{"photo": "/9g"}, "invalid binary: illegal base64 data at input byte 0"},
+		{map[string]interface{}{"int8": json.Number("128")}, "128 overflows int8"},
+		{map[string]interface{}{"int8": float64(1.5)}, "1.5 is not an integer"},
+		{map[string]interface{}{"uint": -1}, "-1 overflows uint"},
+		{map[string]interface{}{"int": json.Number("9223372036854775808")}, "9223372036854775808 overflows int"},
+		{map[string]interface{}{"float32": 1e39}, "1e+39 overflows float32"},
+		{map[string]interface{}{"float64": json.Number("one")}, "invalid number: one"},
+	} {
+		var s scalars
+		err := Unmarshal(test.resource, &s)
+		var errs validate.Errors
+		if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Detail != test.detail {
+			t.Errorf("%v: expected %q, got %v", test.resource, test.detail, err)
+		}
+	}
+}
+
+func TestUnmarshal_marshalers(t *testing.T) {
+	type marshalers struct {
+		Code   roundTripCode
+		Codes  []roundTripCode `scim:",mV"`
+		Status roundTripStatus
+	}
+
+	resource, err := Marshal(marshalers{
+		Code:   1,
+		Codes:  []roundTripCode{2, 3},
+		Status: roundTripStatus{Code: 404},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"code":   "1",
+		"codes":  []interface{}{"2", "3"},
+		"status": json.Number("404"),
+	}
+	if !reflect.DeepEqual(resource, expected) {
+		t.Errorf("expected %v, got %v", expected, resource)
+	}
+
+	var m marshalers
+	if err := Unmarshal(resource, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Code != 1 || !reflect.DeepEqual(m.Codes, []roundTripCode{2, 3}) || m.Status.Code != 404 {
+		t.Errorf("unexpected value %+v", m)
+	}
+
+	err = Unmarshal(map[string]interface{}{
+		"code":   1,
+		"codes":  []interface{}{"a"},
+		"status": "404",
+	}, &m)
+	var errs validate.Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", err)
+	}
+	for i, path := range []string{"code", "codes[0]", "status"} {
+		if errs[i].Path != path {
+			t.Errorf("unexpected error %v", errs[i])
+		}
+	}
+
+	// json.Marshalers that do not produce simple values are encoded based on their kind.
+	resource, err = Marshal(struct{ Config complexConfig }{Config: complexConfig{Meta: complexMeta{A: "x"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]interface{}{
+		"config": map[string]interface{}{"meta": map[string]interface{}{"a": "x"}},
+	}
+	if !reflect.DeepEqual(resource, expected) {
+		t.Errorf("expected %v, got %v", expected, resource)
+	}
+	if _, err := Marshal(struct{ Config schema.ServiceProviderConfig }{Config: schema.ServiceProviderConfig{
+		DocumentationURI: "https://example.com/help/scim.html",
+	}}); err != nil {
+		t.Error(err)
+	}
+}
+
+type complexMeta struct {
+	A string
+}
+
+// complexConfig is encoded as an object by json.Marshaler.
+type complexConfig struct {
+	Meta complexMeta
+}
+
+func (c complexConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"meta": map[string]interface{}{"a": c.Meta.A}})
+}
+
+func TestUnmarshal_extensions(t *testing.T) {
+	const urn = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	for _, resource := range []map[string]interface{}{
+		{
+			urn: map[string]interface{}{
+				"employeeNumber": "701984",
+				"manager":        map[string]interface{}{"givenName": "John"},
+			},
+		},
+		{
+			urn + ":employeeNumber":    "701984",
+			urn + ":manager.givenName": "John",
+		},
+		{
+			urn:                        map[string]interface{}{"employeeNumber": "701984"},
+			urn + ":Manager.givenName": "John",
+		},
+	} {
+		var user roundTripUser
+		if err := UnmarshalStrict(resource, &user); err != nil {
+			t.Fatal(err)
+		}
+		if user.Enterprise == nil || user.Enterprise.EmployeeNumber != "701984" ||
+			user.Enterprise.Manager == nil || user.Enterprise.Manager.GivenName != "John" {
+			t.Errorf("unexpected extension %+v", user.Enterprise)
+		}
+	}
+
+	var user roundTripUser
+	err := UnmarshalStrict(map[string]interface{}{
+		urn:                     map[string]interface{}{"employeeNumber": "701984"},
+		urn + ":EmployeeNumber": "701985",
+		urn + ":costCenter":     "4130",
+	}, &user)
+	var errs validate.Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", err)
+	}
+	for i, path := range []string{urn + ":EmployeeNumber", urn + ":costCenter"} {
+		if errs[i].ScimType != validate.InvalidSyntax || errs[i].Path != path {
+			t.Errorf("unexpected error %v", errs[i])
+		}
+	}
+}
@@ -1,19 +1,31 @@
 package marshal
 
 import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	. "github.com/memsql/scimtools/attributes"
-	"github.com/muir/reflectutils"
 )
 
 var marshalerType = reflect.TypeOf((*Marshaler)(nil)).Elem()
 var idMarshalerType = reflect.TypeOf((*IDMarshaler)(nil)).Elem()
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+var timeType = reflect.TypeOf(time.Time{})
+var numberType = reflect.TypeOf(json.Number(""))
 
 func Marshal(value interface{}) (map[string]interface{}, error) {
-	v := reflect.ValueOf(value)
+	return marshal(reflect.ValueOf(value))
+}
+
+func marshal(v reflect.Value) (map[string]interface{}, error) {
 	if !v.IsValid() {
 		return nil, errors.New("value is invalid")
 	}
@@ -34,39 +46,82 @@ func Marshal(value interface{}) (map[string]interface{}, error) {
 		if v.IsNil() {
 			return nil, errors.New("interface is nil")
 		}
-		return Marshal(v.Elem().Interface())
+		return marshal(v.Elem())
 	case reflect.Ptr:
-		ptr := v.Elem()
-		return Marshal(ptr.Elem().Interface())
+		if v.IsNil() {
+			return nil, errors.New("ptr is nil")
+		}
+		return marshal(v.Elem())
 	case reflect.Struct:
 		resource := make(map[string]interface{})
 
-		t := v.Type()
+		v = addressable(v)
 
-		var err error
-		reflectutils.WalkStructElements(t, func(sf reflect.StructField) bool {
-			tag := parseTags(sf)
-			if tag.ignore {
-				return false
-			}
+		var extensions []string
+		err := encodeFields(resource, v, planOf(t).fields, &extensions)
+		addExtensionSchemas(resource, extensions)
+		return resource, err
+	default:
+		return unsupportedTypeEncoder(v)
+	}
+}
 
-			subField := v.FieldByIndex(sf.Index)
-			if !tag.allowZero && subField.IsZero() {
-				return false
-			}
+// addExtensionSchemas removes the extensions without attributes, and adds the URNs of the others to the schemas
+// attribute of the resource. Resources without schemas attribute are left without one: it would also have to contain
+// the URN of the core schema (RFC 7643 §3), which is not known.
+func addExtensionSchemas(resource map[string]interface{}, extensions []string) {
+	var urns []string
+	for _, urn := range extensions {
+		if m, ok := resource[urn].(map[string]interface{}); ok && len(m) == 0 {
+			delete(resource, urn)
+		} else if _, ok := resource[urn]; ok {
+			urns = append(urns, urn)
+		}
+	}
+	key := ExistingKey("schemas", resource)
+	if value, ok := resource[key]; ok && len(urns) != 0 {
+		resource[key] = extensionSchemas(value, urns)
+	}
+}
 
-			if sf.Anonymous { // it's embedded struct
-				return true
+// extensionSchemas returns the values of the schemas attribute followed by the given URNs, if they are not in it yet.
+func extensionSchemas(value interface{}, urns []string) []interface{} {
+	schemas := append([]interface{}(nil), ToSlice(value)...)
+urns:
+	for _, urn := range urns {
+		for _, s := range schemas {
+			if s, ok := s.(string); ok && strings.EqualFold(s, urn) {
+				continue urns
 			}
-			if e := structEncoder(resource, subField, tag); e != nil {
+		}
+		schemas = append(schemas, urn)
+	}
+	return schemas
+}
+
+// encodeFields encodes the fields of the struct into the resource, the names of the encoded extensions are added to
+// extensions if it is not nil. Returns the last error, if any.
+func encodeFields(resource map[string]interface{}, v reflect.Value, fields []fieldPlan, extensions *[]string) error {
+	var err error
+	for _, f := range fields {
+		field := v.FieldByIndex(f.index)
+		if !f.tag.allowZero && field.IsZero() {
+			continue
+		}
+		if f.embedded {
+			if e := encodeFields(resource, v, f.fields, extensions); e != nil {
 				err = e
 			}
-			return false
-		})
-		return resource, err
-	default:
-		return unsupportedTypeEncoder(v)
+			continue
+		}
+		if extensions != nil && f.tag.extension() {
+			*extensions = append(*extensions, f.tag.name)
+		}
+		if e := structEncoder(resource, field, f.tag); e != nil {
+			err = e
+		}
 	}
+	return err
 }
 
 func structEncoder(resource map[string]interface{}, field reflect.Value, tag tag) error {
@@ -111,6 +166,9 @@ func structEncoderComplexMultiValued(resource map[string]interface{}, field refl
 		if err := structEncoder(value, field, *tag.sub); err != nil {
 			return err
 		}
+		if len(tag.indexes) != 0 {
+			return structEncoderIndexed(resource, value, tag)
+		}
 		EnsureComplexMultiValuedAttribute(resource, tag.name, tag.max())
 		if err := AppendComplexMultiValuedAttribute(resource, tag.name, value); err != nil {
 			return err
@@ -119,14 +177,41 @@ func structEncoderComplexMultiValued(resource map[string]interface{}, field refl
 	return nil
 }
 
+// structEncoderIndexed adds the sub attribute to the values at the indexes of the tag, missing values are added.
+// If the tag selects all indexes, the sub attribute is added to all existing values.
+func structEncoderIndexed(resource map[string]interface{}, value map[string]interface{}, tag tag) error {
+	values, _ := resource[tag.name].([]map[string]interface{})
+	indexes := tag.indexes
+	if tag.all() {
+		indexes = nil
+		for i := range values {
+			indexes = append(indexes, i)
+		}
+	}
+	for _, i := range indexes {
+		for len(values) <= i {
+			values = append(values, make(map[string]interface{}))
+		}
+		if values[i] == nil {
+			values[i] = make(map[string]interface{})
+		}
+		for k, v := range value {
+			if err := Add(values[i], k, v); err != nil {
+				return err
+			}
+		}
+	}
+	resource[tag.name] = values
+	return nil
+}
+
 func structEncoderSimple(resource map[string]interface{}, field reflect.Value, tag tag) error {
 	// Ignore invalid fields.
 	if !field.IsValid() {
 		return nil
 	}
 
-	t := field.Type()
-	if t.Implements(idMarshalerType) {
+	if planOf(field.Type()).receivers[idMarshalerType] == valueReceiver {
 		if field.Kind() == reflect.Ptr && field.IsNil() {
 			return errors.New("ptr is nil")
 		}
@@ -145,6 +230,16 @@ func structEncoderSimple(resource map[string]interface{}, field reflect.Value, t
 		}
 		return nil
 	}
+	value, ok, err := scalarAttribute(field)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if value == nil {
+			return nil
+		}
+		return Add(resource, tag.name, value)
+	}
 
 	switch field.Kind() {
 	// If the simple attribute is a map that means that it is in fact a complex attribute where the name is implicit.
@@ -180,29 +275,8 @@ func structEncoderSimple(resource map[string]interface{}, field reflect.Value, t
 		return structEncoderSimple(resource, field.Elem(), tag)
 	// if it's embeded loop over and just call this func[structEncodesimple] for each element
 	case reflect.Struct:
-		t := field.Type()
 		fieldStruct := make(map[string]interface{})
-		var err error
-		reflectutils.WalkStructElements(t, func(sf reflect.StructField) bool {
-			tagIndex := parseTags(sf)
-			if tagIndex.ignore {
-				return false
-			}
-
-			subField := field.FieldByIndex(sf.Index)
-			if !tagIndex.allowZero && subField.IsZero() {
-				return false
-			}
-
-			if sf.Anonymous { // it's embedded struct
-				return true
-			}
-			if e := structEncoder(fieldStruct, subField, tagIndex); e != nil {
-				err = e
-			}
-			return false
-		})
-		if err != nil {
+		if err := encodeFields(fieldStruct, field, planOf(field.Type()).fields, nil); err != nil {
 			return err
 		}
 
@@ -232,6 +306,10 @@ func structEncoderSimple(resource map[string]interface{}, field reflect.Value, t
 }
 
 func structEncoderSimpleMultiValued(resource map[string]interface{}, field reflect.Value, tag tag) error {
+	if field.IsValid() && isScalar(field.Type()) {
+		// Date times and binary values are a single value, even though they are a struct or a slice.
+		return structEncoderSimpleValue(resource, field, tag)
+	}
 	switch field.Kind() {
 	case reflect.Array, reflect.Slice:
 		for i := 0; i < field.Len(); i++ {
@@ -240,12 +318,11 @@ func structEncoderSimpleMultiValued(resource map[string]interface{}, field refle
 				return err
 			}
 			for _, v := range value {
-				switch field.Index(i).Kind() {
-				case reflect.Struct:
-					EnsureComplexMultiValuedAttribute(resource, tag.name, tag.max())
-					if err := AppendComplexMultiValuedAttribute(resource, tag.name, v.(map[string]interface{})); err != nil {
-						return err
-					}
+				switch m, ok := v.(map[string]interface{}); {
+				case ok && derefType(field.Index(i).Type()).Kind() == reflect.Struct:
+					// Every struct is a separate value, their attributes should not be merged.
+					values, _ := resource[tag.name].([]map[string]interface{})
+					resource[tag.name] = append(values, m)
 				default:
 					EnsureMultiValuedAttribute(resource, tag.name, tag.max())
 					if err := AppendMultiValuedAttribute(resource, tag.name, v); err != nil {
@@ -268,20 +345,13 @@ func structEncoderSimpleMultiValued(resource map[string]interface{}, field refle
 	case reflect.Ptr, reflect.Interface:
 		return structEncoderSimpleMultiValued(resource, field.Elem(), tag)
 	case reflect.Struct:
-		EnsureComplexMultiValuedAttribute(resource, tag.name, tag.max())
 		fieldStruct := make(map[string]interface{})
-		t := field.Type()
-		for i := 0; i < field.NumField(); i++ {
-			tagIndex := parseTags(t.Field(i))
-			if tagIndex.ignore {
+		for _, f := range planOf(field.Type()).fields {
+			fieldIndex := field.FieldByIndex(f.index)
+			if !f.tag.allowZero && fieldIndex.IsZero() {
 				continue
 			}
-
-			fieldIndex := field.Field(i)
-			if !tagIndex.allowZero && fieldIndex.IsZero() {
-				continue
-			}
-			if err := structEncoder(fieldStruct, fieldIndex, tagIndex); err != nil {
+			if err := structEncoder(fieldStruct, fieldIndex, f.tag); err != nil {
 				return err
 			}
 		}
@@ -289,20 +359,25 @@ func structEncoderSimpleMultiValued(resource map[string]interface{}, field refle
 			return fmt.Errorf("nested depth exceeded: %d", depth)
 		}
 
-		if err := AppendComplexMultiValuedAttribute(resource, tag.name, fieldStruct); err != nil {
-			return err
-		}
+		values, _ := resource[tag.name].([]map[string]interface{})
+		resource[tag.name] = append(values, fieldStruct)
 	default:
-		EnsureMultiValuedAttribute(resource, tag.name, tag.max())
-		value := make(map[string]interface{})
-		if err := structEncoderSimple(value, field, tag); err != nil {
+		return structEncoderSimpleValue(resource, field, tag)
+	}
+	return nil
+}
+
+// structEncoderSimpleValue appends the simple value of the field to the multi valued attribute.
+func structEncoderSimpleValue(resource map[string]interface{}, field reflect.Value, tag tag) error {
+	EnsureMultiValuedAttribute(resource, tag.name, tag.max())
+	value := make(map[string]interface{})
+	if err := structEncoderSimple(value, field, tag); err != nil {
+		return err
+	}
+	for _, v := range value {
+		if err := AppendMultiValuedAttribute(resource, tag.name, v); err != nil {
 			return err
 		}
-		for _, v := range value {
-			if err := AppendMultiValuedAttribute(resource, tag.name, v); err != nil {
-				return err
-			}
-		}
 	}
 	return nil
 }
@@ -319,6 +394,9 @@ func validSimpleAttribute(v reflect.Value) (interface{}, error) {
 
 		v = v.Elem()
 	}
+	if value, ok, err := scalarAttribute(v); ok || err != nil {
+		return value, err
+	}
 
 	switch v.Kind() {
 	case reflect.Bool:
@@ -336,6 +414,95 @@ func validSimpleAttribute(v reflect.Value) (interface{}, error) {
 	}
 }
 
+// scalarAttribute returns the value of the simple attributes that are not encoded based on their kind, in this order:
+//   - date times as RFC 3339 strings (dateTime),
+//   - encoding.TextMarshalers as strings,
+//   - json.Marshalers as the JSON value they produce if it is simple (nil for JSON null),
+//   - other byte slices as base64 strings (binary),
+//   - json numbers as is.
+//
+// Other values, including json.Marshalers that do not produce a simple value, are encoded based on their kind.
+func scalarAttribute(v reflect.Value) (interface{}, bool, error) {
+	if !v.IsValid() || v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		return nil, false, nil
+	}
+	if v.Type() == timeType && v.CanInterface() {
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), true, nil
+	}
+	if m, ok := implements(v, textMarshalerType); ok {
+		text, err := m.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, false, err
+		}
+		return string(text), true, nil
+	}
+	if m, ok := implements(v, jsonMarshalerType); ok {
+		raw, err := m.(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return nil, false, err
+		}
+		d := json.NewDecoder(bytes.NewReader(raw))
+		d.UseNumber()
+		var value interface{}
+		if err := d.Decode(&value); err != nil {
+			return nil, false, err
+		}
+		switch value.(type) {
+		case nil, bool, string, json.Number:
+			return value, true, nil
+		}
+	}
+
+	switch t := v.Type(); {
+	case isBinary(t):
+		return base64.StdEncoding.EncodeToString(v.Bytes()), true, nil
+	case t == numberType:
+		return json.Number(v.String()), true, nil
+	}
+	return nil, false, nil
+}
+
+// implements returns the value, or its address, as an interface of the given type if it implements it.
+func implements(v reflect.Value, t reflect.Type) (interface{}, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+	switch planOf(v.Type()).receivers[t] {
+	case valueReceiver:
+		return v.Interface(), true
+	case pointerReceiver:
+		if v.CanAddr() {
+			return v.Addr().Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// addressable returns an addressable copy of the value if the value itself is not addressable, so that the marshalers
+// with pointer receivers of its fields are used.
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
+
+// isScalar returns whether values of the type are simple attributes, even though their kind is not.
+func isScalar(t reflect.Type) bool {
+	return planOf(t).scalar
+}
+
+// implementsType returns whether the type, or a pointer to it, implements the given interface.
+func implementsType(t, i reflect.Type) bool {
+	return t.Implements(i) || reflect.PtrTo(t).Implements(i)
+}
+
+func isBinary(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
 // Marshaler is the interface implemented by types that can marshal themselves into SCIM resources.
 type Marshaler interface {
 	MarshalSCIM() (map[string]interface{}, error)
@@ -2,6 +2,7 @@ package marshal
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -306,3 +307,67 @@ func TestSimpleMultiValued(t *testing.T) {
 		t.Error(fmt.Sprintf("\n%#v", resource), fmt.Sprintf("\n%#v", ref))
 	}
 }
+
+func TestExtensions(t *testing.T) {
+	type enterprise struct {
+		EmployeeNumber string
+		Manager        string `scim:"manager/value"`
+	}
+	type custom struct {
+		Level int
+	}
+	type user struct {
+		Schemas    []string `scim:",mV"`
+		UserName   string
+		Enterprise *enterprise `scim:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`
+		Custom     custom      `scim:"urn:example:params:scim:schemas:extension:custom:2.0:User,zero"`
+	}
+	const urn = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+
+	resource, err := Marshal(user{
+		Schemas:    []string{"urn:ietf:params:scim:schemas:core:2.0:User"},
+		UserName:   "bjensen",
+		Enterprise: &enterprise{EmployeeNumber: "701984"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := map[string]interface{}{
+		"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User", urn},
+		"userName": "bjensen",
+		urn:        map[string]interface{}{"employeeNumber": "701984"},
+	}
+	if fmt.Sprintf("%v", resource) != fmt.Sprintf("%v", ref) {
+		t.Error(fmt.Sprintf("\n%#v", resource), fmt.Sprintf("\n%#v", ref))
+	}
+
+	// Empty extensions are not added.
+	resource, err = Marshal(user{UserName: "bjensen", Enterprise: &enterprise{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%v", resource) != "map[userName:bjensen]" {
+		t.Errorf("unexpected resource %v", resource)
+	}
+
+	// The schemas attribute is not created, it would not contain the core schema.
+	resource, err = Marshal(user{UserName: "bjensen", Enterprise: &enterprise{EmployeeNumber: "701984"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resource["schemas"]; ok {
+		t.Errorf("unexpected schemas %v", resource["schemas"])
+	}
+
+	// URNs that are already present are not added again.
+	resource, err = Marshal(user{
+		Schemas:    []string{"urn:ietf:params:scim:schemas:core:2.0:User", strings.ToUpper(urn)},
+		Enterprise: &enterprise{EmployeeNumber: "701984"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schemas, ok := resource["schemas"].([]interface{}); !ok || len(schemas) != 2 {
+		t.Errorf("unexpected schemas %#v", resource["schemas"])
+	}
+}
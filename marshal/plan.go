@@ -0,0 +1,158 @@
+package marshal
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/muir/reflectutils"
+)
+
+// plans caches the plans of the types that are encoded or decoded, by type.
+var plans sync.Map // map[reflect.Type]*plan
+
+// plan contains what needs to be known about a type to encode or decode its values, so tags are parsed and types are
+// analyzed only once per type.
+type plan struct {
+	// fields contains the fields of a struct type that are not ignored, in the order they are declared.
+	fields []fieldPlan
+	// groups contains the fields of a struct type, including the fields of embedded structs, grouped by the (case
+	// insensitive) name of their attribute.
+	groups []fieldGroup
+	// receivers contains how the type implements the (un)marshaler interfaces, by interface.
+	receivers map[reflect.Type]receiver
+	// scalar is true if values of the type are simple attributes, even though their kind is not.
+	scalar bool
+}
+
+// fieldPlan describes a single field of a struct.
+type fieldPlan struct {
+	// index is the index of the field within the struct that the plan belongs to.
+	index    []int
+	tag      tag
+	exported bool
+	// list is true if the field (or the value it points to) is a slice or an array.
+	list bool
+	// embedded is true for embedded fields, the fields of embedded structs are in fields.
+	embedded bool
+	fields   []fieldPlan
+}
+
+// fieldGroup contains the fields that (partly) encode the same attribute, in the order they are declared.
+type fieldGroup []groupedField
+
+// groupedField is a field of a fieldGroup.
+type groupedField struct {
+	*fieldPlan
+	// embedded contains the indexes of the embedded structs that contain the field, and are not encoded if zero.
+	embedded [][]int
+}
+
+// receiver describes whether a value, or only a pointer to it, implements an interface.
+type receiver int
+
+const (
+	noReceiver receiver = iota
+	valueReceiver
+	pointerReceiver
+)
+
+// planInterfaces are the interfaces that are checked by the encoder and decoder.
+var planInterfaces = []reflect.Type{
+	idMarshalerType,
+	ummarshalluuidType,
+	textMarshalerType,
+	textUnmarshalerType,
+	jsonMarshalerType,
+	jsonUnmarshalerType,
+}
+
+// planOf returns the (cached) plan of the given type.
+func planOf(t reflect.Type) *plan {
+	if p, ok := plans.Load(t); ok {
+		return p.(*plan)
+	}
+	p := newPlan(t)
+	actual, _ := plans.LoadOrStore(t, p)
+	return actual.(*plan)
+}
+
+func newPlan(t reflect.Type) *plan {
+	p := &plan{
+		receivers: make(map[reflect.Type]receiver, len(planInterfaces)),
+	}
+	for _, i := range planInterfaces {
+		switch {
+		case t.Implements(i):
+			p.receivers[i] = valueReceiver
+		case reflect.PtrTo(t).Implements(i):
+			p.receivers[i] = pointerReceiver
+		}
+	}
+	if p.receivers[textMarshalerType] != noReceiver || p.receivers[textUnmarshalerType] != noReceiver {
+		p.scalar = true
+	}
+	// Structs that implement the json (un)marshalers can still be complex attributes.
+	if t.Kind() != reflect.Struct && (p.receivers[jsonMarshalerType] != noReceiver || p.receivers[jsonUnmarshalerType] != noReceiver) {
+		p.scalar = true
+	}
+	if t == timeType || isBinary(t) {
+		p.scalar = true
+	}
+	if t.Kind() == reflect.Struct {
+		p.fields = planFields(t, nil)
+		p.groups = groupFields(nil, p.fields, nil)
+	}
+	return p
+}
+
+// planFields returns the fields of the struct, the index is the index of the struct itself if it is embedded.
+func planFields(t reflect.Type, index []int) []fieldPlan {
+	var fields []fieldPlan
+	reflectutils.WalkStructElements(t, func(sf reflect.StructField) bool {
+		tag := parseTags(sf)
+		if tag.ignore {
+			return false
+		}
+		f := fieldPlan{
+			index:    append(append([]int{}, index...), sf.Index...),
+			tag:      tag,
+			exported: sf.IsExported(),
+			list:     isList(sf.Type),
+			embedded: sf.Anonymous,
+		}
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			f.fields = planFields(sf.Type, f.index)
+		}
+		fields = append(fields, f)
+		return false
+	})
+	return fields
+}
+
+// groupFields adds the fields to the groups of their attribute, the fields of embedded structs are added recursively.
+func groupFields(groups []fieldGroup, fields []fieldPlan, embedded [][]int) []fieldGroup {
+	for i := range fields {
+		f := &fields[i]
+		if f.embedded {
+			e := embedded
+			if !f.tag.allowZero {
+				e = append(append([][]int{}, embedded...), f.index)
+			}
+			groups = groupFields(groups, f.fields, e)
+			continue
+		}
+		field := groupedField{fieldPlan: f, embedded: embedded}
+		j := 0
+		for ; j < len(groups); j++ {
+			if strings.EqualFold(groups[j][0].tag.name, f.tag.name) {
+				break
+			}
+		}
+		if j == len(groups) {
+			groups = append(groups, nil)
+		}
+		groups[j] = append(groups[j], field)
+	}
+	return groups
+}
@@ -0,0 +1,183 @@
+package marshal
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type benchmarkEmail struct {
+	Value   string
+	Type    string
+	Primary bool
+}
+
+type benchmarkEnterprise struct {
+	EmployeeNumber string
+	CostCenter     string
+	Manager        string `scim:"manager/value"`
+}
+
+type benchmarkUser struct {
+	ID          string `scim:"id"`
+	ExternalID  string `scim:"externalId"`
+	UserName    string
+	DisplayName string
+	GivenName   string           `scim:"name/givenName"`
+	FamilyName  string           `scim:"name/familyName"`
+	Active      bool             `scim:",zero"`
+	Emails      []benchmarkEmail `scim:",mV"`
+	WorkPhone   string           `scim:"phoneNumbers/value,mV,index=0"`
+	Groups      []string         `scim:",mV"`
+	Created     time.Time
+	Enterprise  *benchmarkEnterprise `scim:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`
+}
+
+var benchmarkValue = benchmarkUser{
+	ID:          "2819c223-7f76-453a-919d-413861904646",
+	ExternalID:  "bjensen",
+	UserName:    "bjensen@example.com",
+	DisplayName: "Babs Jensen",
+	GivenName:   "Barbara",
+	FamilyName:  "Jensen",
+	Active:      true,
+	Emails: []benchmarkEmail{
+		{Value: "bjensen@example.com", Type: "work", Primary: true},
+		{Value: "babs@jensen.org", Type: "home"},
+	},
+	WorkPhone: "555-555-5555",
+	Groups:    []string{"admin", "users"},
+	Created:   time.Date(2010, 1, 23, 4, 56, 22, 0, time.UTC),
+	Enterprise: &benchmarkEnterprise{
+		EmployeeNumber: "701984",
+		CostCenter:     "4130",
+		Manager:        "26118915-6090-4610-87e4-49d8ca9f808d",
+	},
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Marshal(benchmarkValue); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	resource, err := Marshal(benchmarkValue)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var user benchmarkUser
+		if err := Unmarshal(resource, &user); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// The uncached benchmarks clear the plans before every call, so every call also builds the plans of the types it
+// encounters: the difference with BenchmarkMarshal and BenchmarkUnmarshal is the cost of building plans. They do not
+// run the implementation from before plans were introduced, which analyzed the types without building plans.
+//
+//	go test -run '^$' -bench 'Marshal|Unmarshal' -benchmem ./marshal
+func BenchmarkMarshal_uncached(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		clearPlans()
+		if _, err := Marshal(benchmarkValue); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshal_uncached(b *testing.B) {
+	resource, err := Marshal(benchmarkValue)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		clearPlans()
+		var user benchmarkUser
+		if err := Unmarshal(resource, &user); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func clearPlans() {
+	plans.Range(func(t, _ interface{}) bool {
+		plans.Delete(t)
+		return true
+	})
+}
+
+func TestPlanOf(t *testing.T) {
+	typ := reflect.TypeOf(benchmarkUser{})
+
+	var wg sync.WaitGroup
+	plans := make([]*plan, 8)
+	for i := range plans {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			plans[i] = planOf(typ)
+			if _, err := Marshal(benchmarkValue); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, p := range plans {
+		if p != plans[0] {
+			t.Fatal("expected the plan to be cached")
+		}
+	}
+
+	p := plans[0]
+	if len(p.fields) != typ.NumField() {
+		t.Errorf("unexpected fields %+v", p.fields)
+	}
+	if f := planField(p.fields, "name", "givenName"); f == nil {
+		t.Error("expected a plan for name.givenName")
+	}
+	if f := planField(p.fields, "emails", ""); f == nil || !f.list {
+		t.Errorf("unexpected plan for emails %+v", f)
+	}
+	// Only the sub attributes of name are grouped.
+	if g := planGroup(p.groups, "name"); len(p.groups) != len(p.fields)-1 || len(g) != 2 || g[1].tag.sub.name != "familyName" {
+		t.Errorf("unexpected groups %+v", p.groups)
+	}
+	if !planOf(reflect.TypeOf(time.Time{})).scalar || planOf(reflect.TypeOf(roundTripCode(0))).receivers[textUnmarshalerType] != pointerReceiver {
+		t.Error("unexpected scalar plans")
+	}
+}
+
+// planField returns the plan of the field of the attribute with the given name and sub attribute, nil if none.
+func planField(fields []fieldPlan, name, sub string) *fieldPlan {
+	for i, f := range fields {
+		if f.tag.name != name {
+			continue
+		}
+		if f.tag.sub == nil && sub == "" || f.tag.sub != nil && f.tag.sub.name == sub {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// planGroup returns the group of the fields of the attribute with the given name, nil if none.
+func planGroup(groups []fieldGroup, name string) fieldGroup {
+	for _, g := range groups {
+		if g[0].tag.name == name {
+			return g
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package marshal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/memsql/scimtools/attributes"
+	"github.com/memsql/scimtools/filter"
+	"github.com/memsql/scimtools/schema"
+)
+
+// Projection selects the attributes that are returned, as requested by the "attributes" and "excludedAttributes"
+// parameters (RFC 7644 §3.4.2.5). The names are attribute paths (e.g. "name.givenName"), optionally prefixed with the
+// URN of their schema. The URN of an extension selects all the attributes of that extension.
+// If attributes are listed, the excluded attributes are ignored.
+type Projection struct {
+	Attributes         []string
+	ExcludedAttributes []string
+}
+
+// NewProjection returns the projection of the given (comma separated) query parameters.
+func NewProjection(attributes, excludedAttributes string) Projection {
+	split := func(s string) []string {
+		var names []string
+		for _, name := range strings.Split(s, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	return Projection{
+		Attributes:         split(attributes),
+		ExcludedAttributes: split(excludedAttributes),
+	}
+}
+
+// MarshalWithSchema marshals the value and projects the resulting resource, see Project.
+func MarshalWithSchema(value interface{}, projection Projection, s schema.ReferenceSchema, ext ...schema.ReferenceSchema) (map[string]interface{}, error) {
+	resource, err := Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return Project(resource, projection, s, ext...)
+}
+
+// Project returns a copy of the resource that only contains the attributes that should be returned (RFC 7643 §7):
+// - attributes that are always returned (e.g. id) are included, even if excluded.
+// - attributes that are never returned, or write only (e.g. password), are not included.
+// - attributes that are returned on request are only included if they are listed in the attributes.
+// - all other attributes are included if listed in the attributes, or if no attributes are listed and they are not excluded.
+//
+// Attributes that are not defined by the schemas are not included. Complex attributes without any remaining sub
+// attributes are removed. Returns an error if the projection contains invalid attribute paths.
+func Project(resource map[string]interface{}, projection Projection, s schema.ReferenceSchema, ext ...schema.ReferenceSchema) (map[string]interface{}, error) {
+	p := projector{s: s, ext: ext}
+	var err error
+	if p.attributes, err = p.keys(projection.Attributes); err != nil {
+		return nil, err
+	}
+	if p.excluded, err = p.keys(projection.ExcludedAttributes); err != nil {
+		return nil, err
+	}
+	if len(p.attributes) != 0 {
+		p.excluded = nil
+	}
+
+	projected := make(map[string]interface{})
+	for k, value := range resource {
+		if e, ok := p.extension(k); ok {
+			key := strings.ToLower(e.ID)
+			if v, ok := p.attribute(key, &schema.Attribute{
+				Name:          e.ID,
+				Type:          schema.ComplexType,
+				SubAttributes: e.Attributes,
+			}, value, false, false); ok {
+				projected[k] = v
+			}
+			continue
+		}
+
+		attribute := attributes.FindAttribute(k, s.Attributes)
+		if attribute == nil {
+			attribute = attributes.FindAttribute(k, schema.CoreAttributes)
+		}
+		if attribute == nil {
+			continue
+		}
+		if v, ok := p.attribute(strings.ToLower(s.ID+":"+attribute.Name), attribute, value, false, false); ok {
+			projected[k] = v
+		}
+	}
+	return projected, nil
+}
+
+type projector struct {
+	s   schema.ReferenceSchema
+	ext []schema.ReferenceSchema
+
+	// attributes and excluded contain the (lower case) fully qualified names of the selected attributes.
+	attributes map[string]bool
+	excluded   map[string]bool
+}
+
+// attribute returns the projected value of the attribute with the given key, false if it should not be returned.
+// The parent arguments indicate whether the (complex) parent attribute was selected or excluded as a whole.
+func (p projector) attribute(key string, attribute *schema.Attribute, value interface{}, parentSelected, parentExcluded bool) (interface{}, bool) {
+	if attribute.Returned == schema.Never || attribute.Mutability == schema.WriteOnly || value == nil {
+		return nil, false
+	}
+
+	selected := parentSelected || p.attributes[key]
+	excluded := parentExcluded || p.excluded[key]
+	switch {
+	case attribute.Returned == schema.Always || attribute == schema.SchemasAttribute:
+		selected, excluded = true, false
+	case attribute.Returned == schema.Request:
+		if !p.attributes[key] && !p.partial(key) {
+			return nil, false
+		}
+	case len(p.attributes) != 0:
+		// Complex attributes can still contain sub attributes that are always returned.
+		if !selected && !p.partial(key) && attribute.Type != schema.ComplexType {
+			return nil, false
+		}
+	}
+	if attribute.Type != schema.ComplexType {
+		if excluded {
+			return nil, false
+		}
+		return value, true
+	}
+
+	sep := "."
+	if strings.Contains(attribute.Name, ":") {
+		// Attributes of extensions.
+		sep = ":"
+	}
+	project := func(element interface{}) (map[string]interface{}, bool) {
+		m, ok := toMap(element)
+		if !ok {
+			return nil, false
+		}
+		projected := make(map[string]interface{})
+		for k, v := range m {
+			sub := attributes.FindAttribute(k, attribute.SubAttributes)
+			if sub == nil {
+				continue
+			}
+			if v, ok := p.attribute(key+sep+strings.ToLower(sub.Name), sub, v, selected, excluded); ok {
+				projected[k] = v
+			}
+		}
+		return projected, len(projected) != 0
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return project(value)
+	}
+	var values []map[string]interface{}
+	for i := 0; i < v.Len(); i++ {
+		if m, ok := project(v.Index(i).Interface()); ok {
+			values = append(values, m)
+		}
+	}
+	return values, len(values) != 0
+}
+
+// partial returns whether any of the sub attributes of the attribute with the given key is selected.
+func (p projector) partial(key string) bool {
+	for k := range p.attributes {
+		if strings.HasPrefix(k, key+".") || strings.HasPrefix(k, key+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// keys returns the fully qualified names of the given attribute paths. Unknown attributes are ignored.
+func (p projector) keys(names []string) (map[string]bool, error) {
+	keys := make(map[string]bool)
+	for _, name := range names {
+		if e, ok := p.extension(name); ok {
+			keys[strings.ToLower(e.ID)] = true
+			continue
+		}
+
+		path, err := filter.ParsePath(name)
+		if err != nil {
+			return nil, err
+		}
+		if path.ValueFilter != nil {
+			return nil, fmt.Errorf("invalid attribute %q: value filters are not allowed", name)
+		}
+		uri := path.AttributePath.URI
+		if uri == "" {
+			uri = p.s.ID
+			if attributes.FindAttribute(path.AttributePath.AttributeName, p.s.Attributes) == nil && attributes.FindAttribute(path.AttributePath.AttributeName, schema.CoreAttributes) == nil {
+				for _, e := range p.ext {
+					if attributes.FindAttribute(path.AttributePath.AttributeName, e.Attributes) != nil {
+						uri = e.ID
+						break
+					}
+				}
+			}
+		}
+		key := uri + ":" + path.AttributePath.AttributeName
+		if path.AttributePath.SubAttribute != "" {
+			key += "." + path.AttributePath.SubAttribute
+		}
+		keys[strings.ToLower(key)] = true
+	}
+	return keys, nil
+}
+
+func (p projector) extension(urn string) (schema.ReferenceSchema, bool) {
+	for _, e := range p.ext {
+		if strings.EqualFold(urn, e.ID) {
+			return e, true
+		}
+	}
+	return schema.ReferenceSchema{}, false
+}
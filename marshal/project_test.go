@@ -0,0 +1,225 @@
+package marshal
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/memsql/scimtools/schema"
+)
+
+func ExampleMarshalWithSchema() {
+	type User struct {
+		ID       string `scim:"id"`
+		UserName string
+		Password string
+		Name     struct {
+			GivenName  string
+			FamilyName string
+		}
+	}
+	user := User{ID: "2819c223", UserName: "bjensen", Password: "t1meMa$heen"}
+	user.Name.GivenName, user.Name.FamilyName = "Barbara", "Jensen"
+
+	for _, projection := range []Projection{
+		{},
+		NewProjection("name.familyName", ""),
+		NewProjection("", "userName, name"),
+	} {
+		resource, _ := MarshalWithSchema(user, projection, schema.UserSchema)
+		raw, _ := json.Marshal(resource)
+		fmt.Println(string(raw))
+	}
+
+	// Output:
+	// {"id":"2819c223","name":{"familyName":"Jensen","givenName":"Barbara"},"userName":"bjensen"}
+	// {"id":"2819c223","name":{"familyName":"Jensen"}}
+	// {"id":"2819c223"}
+}
+
+var projectSchema = schema.ReferenceSchema{
+	ID: "urn:ietf:params:scim:schemas:core:2.0:User",
+	Attributes: []*schema.Attribute{
+		{Name: "userName", Type: schema.StringType},
+		{Name: "password", Type: schema.StringType, Mutability: schema.WriteOnly, Returned: schema.Never},
+		{Name: "secret", Type: schema.StringType, Mutability: schema.WriteOnly},
+		{Name: "groups", Type: schema.StringType, MultiValued: true, Returned: schema.Request},
+		{Name: "name", Type: schema.ComplexType, SubAttributes: []*schema.Attribute{
+			{Name: "givenName", Type: schema.StringType},
+			{Name: "familyName", Type: schema.StringType, Returned: schema.Always},
+			{Name: "phonetic", Type: schema.StringType, Returned: schema.Request},
+		}},
+		{Name: "emails", Type: schema.ComplexType, MultiValued: true, SubAttributes: []*schema.Attribute{
+			{Name: "value", Type: schema.StringType},
+			{Name: "type", Type: schema.StringType},
+		}},
+	},
+}
+
+var projectExtension = schema.ReferenceSchema{
+	ID: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+	Attributes: []*schema.Attribute{
+		{Name: "employeeNumber", Type: schema.StringType},
+		{Name: "costCenter", Type: schema.StringType, Returned: schema.Request},
+	},
+}
+
+type projectEmail struct {
+	Value string
+	Type  string
+}
+
+type projectEnterpriseUser struct {
+	EmployeeNumber string
+	CostCenter     string
+}
+
+type projectUser struct {
+	Schemas    []string `scim:",mV"`
+	ID         string   `scim:"id"`
+	UserName   string
+	Password   string
+	Secret     string
+	Groups     []string       `scim:",mV"`
+	GivenName  string         `scim:"name/givenName"`
+	FamilyName string         `scim:"name/familyName"`
+	Phonetic   string         `scim:"name/phonetic"`
+	Emails     []projectEmail `scim:",mV"`
+	Unknown    string
+	Enterprise projectEnterpriseUser `scim:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`
+}
+
+func TestProject(t *testing.T) {
+	user := projectUser{
+		Schemas:    []string{projectSchema.ID, projectExtension.ID},
+		ID:         "0",
+		UserName:   "bjensen",
+		Password:   "t1meMa$heen",
+		Secret:     "secret",
+		Groups:     []string{"admin"},
+		GivenName:  "Barbara",
+		FamilyName: "Jensen",
+		Phonetic:   "bɑːbərə",
+		Emails:     []projectEmail{{Value: "bjensen@example.com", Type: "work"}},
+		Unknown:    "unknown",
+		Enterprise: projectEnterpriseUser{EmployeeNumber: "701984", CostCenter: "4130"},
+	}
+	schemas := []interface{}{projectSchema.ID, projectExtension.ID}
+	emails := []map[string]interface{}{{"value": "bjensen@example.com", "type": "work"}}
+
+	for _, test := range []struct {
+		name       string
+		projection Projection
+		expected   map[string]interface{}
+	}{
+		{
+			name: "default",
+			expected: map[string]interface{}{
+				"schemas":           schemas,
+				"id":                "0",
+				"userName":          "bjensen",
+				"name":              map[string]interface{}{"givenName": "Barbara", "familyName": "Jensen"},
+				"emails":            emails,
+				projectExtension.ID: map[string]interface{}{"employeeNumber": "701984"},
+			},
+		},
+		{
+			name:       "attributes",
+			projection: NewProjection("userName,groups,name.phonetic,emails.value", ""),
+			expected: map[string]interface{}{
+				"schemas":  schemas,
+				"id":       "0",
+				"userName": "bjensen",
+				"groups":   []interface{}{"admin"},
+				"name":     map[string]interface{}{"familyName": "Jensen", "phonetic": "bɑːbərə"},
+				"emails":   []map[string]interface{}{{"value": "bjensen@example.com"}},
+			},
+		},
+		{
+			name:       "fully qualified attributes",
+			projection: NewProjection("urn:ietf:params:scim:schemas:core:2.0:User:NAME,urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:costCenter", ""),
+			expected: map[string]interface{}{
+				"schemas":           schemas,
+				"id":                "0",
+				"name":              map[string]interface{}{"givenName": "Barbara", "familyName": "Jensen"},
+				projectExtension.ID: map[string]interface{}{"costCenter": "4130"},
+			},
+		},
+		{
+			name:       "extension",
+			projection: NewProjection(projectExtension.ID+",password,secret", ""),
+			expected: map[string]interface{}{
+				"schemas":           schemas,
+				"id":                "0",
+				"name":              map[string]interface{}{"familyName": "Jensen"},
+				projectExtension.ID: map[string]interface{}{"employeeNumber": "701984"},
+			},
+		},
+		{
+			name:       "excluded attributes",
+			projection: NewProjection("", "id,schemas,userName,name,emails.type,employeeNumber"),
+			expected: map[string]interface{}{
+				"schemas": schemas,
+				"id":      "0",
+				"name":    map[string]interface{}{"familyName": "Jensen"},
+				"emails":  []map[string]interface{}{{"value": "bjensen@example.com"}},
+			},
+		},
+		{
+			name:       "excluded extension",
+			projection: NewProjection("", projectExtension.ID),
+			expected: map[string]interface{}{
+				"schemas":  schemas,
+				"id":       "0",
+				"userName": "bjensen",
+				"name":     map[string]interface{}{"givenName": "Barbara", "familyName": "Jensen"},
+				"emails":   emails,
+			},
+		},
+		{
+			name:       "attributes take precedence",
+			projection: NewProjection("userName", "userName"),
+			expected: map[string]interface{}{
+				"schemas":  schemas,
+				"id":       "0",
+				"userName": "bjensen",
+				"name":     map[string]interface{}{"familyName": "Jensen"},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			resource, err := MarshalWithSchema(user, test.projection, projectSchema, projectExtension)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Compare the JSON representation, to ignore the difference between slice types.
+			if !jsonEqual(t, resource, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, resource)
+			}
+		})
+	}
+}
+
+func TestProject_invalid(t *testing.T) {
+	for _, name := range []string{"emails[type eq \"work\"]", "name..givenName", ""} {
+		if _, err := Project(map[string]interface{}{}, Projection{Attributes: []string{name}}, projectSchema); err == nil {
+			t.Errorf("expected an error for %q", name)
+		}
+	}
+}
+
+func jsonEqual(t *testing.T, a, b interface{}) bool {
+	t.Helper()
+	var values [2]interface{}
+	for i, v := range []interface{}{a, b} {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(raw, &values[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return reflect.DeepEqual(values[0], values[1])
+}
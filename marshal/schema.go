@@ -0,0 +1,210 @@
+package marshal
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/memsql/scimtools/attributes"
+	"github.com/memsql/scimtools/schema"
+	"github.com/muir/reflectutils"
+)
+
+// InferSchema returns the reference schema of the resources that Marshal produces for values of the same type as the
+// given struct (or pointer to a struct). The attributes are based on the same tags that are used by Marshal, the
+// characteristics of an attribute can be set with the following tag options (prefixed with "_" for sub attributes):
+// "required", "caseExact", "mutability=<mutability>", "returned=<returned>" and "description=<description>".
+// Descriptions that contain commas have to be quoted with single quotes: "description='<description>'".
+//
+// Maps can not be inferred, because the sub attributes their keys are encoded as are not known. Fields of which the
+// type is a map have to be ignored.
+//
+// Fields named after an URN (e.g. `scim:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`) are returned
+// as schema extensions, named after the type of the field. The common attributes (id, externalId, meta and schemas)
+// are not part of the schema.
+func InferSchema(id, name string, value interface{}) (schema.ReferenceSchema, []schema.ReferenceSchema, error) {
+	t := reflect.TypeOf(value)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return schema.ReferenceSchema{}, nil, errors.New("value is not a struct")
+	}
+
+	s := schema.ReferenceSchema{ID: id, Name: name}
+	var extensions []schema.ReferenceSchema
+	var err error
+	reflectutils.WalkStructElements(t, func(sf reflect.StructField) bool {
+		tag := parseTags(sf)
+		if tag.ignore || err != nil {
+			return false
+		}
+		if sf.Anonymous {
+			return true
+		}
+
+		if tag.extension() {
+			e := schema.ReferenceSchema{ID: tag.name, Name: derefType(sf.Type).Name()}
+			if e.Attributes, err = inferAttributes(derefType(sf.Type)); err != nil {
+				err = fmt.Errorf("%s: %s", tag.name, err)
+			}
+			extensions = append(extensions, e)
+			return false
+		}
+		if isCommonAttribute(tag.name) {
+			return false
+		}
+		s.Attributes, err = addField(s.Attributes, sf.Type, tag)
+		return false
+	})
+	if err != nil {
+		return schema.ReferenceSchema{}, nil, err
+	}
+
+	for _, s := range append([]schema.ReferenceSchema{s}, extensions...) {
+		if err := schema.Lint(s); err != nil {
+			return schema.ReferenceSchema{}, nil, err
+		}
+	}
+	return s, extensions, nil
+}
+
+// inferAttributes returns the attributes of the fields of the given struct.
+func inferAttributes(t reflect.Type) ([]*schema.Attribute, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is not a struct", t)
+	}
+	var (
+		attrs []*schema.Attribute
+		err   error
+	)
+	reflectutils.WalkStructElements(t, func(sf reflect.StructField) bool {
+		tag := parseTags(sf)
+		if tag.ignore || err != nil {
+			return false
+		}
+		if sf.Anonymous {
+			return true
+		}
+		attrs, err = addField(attrs, sf.Type, tag)
+		return false
+	})
+	return attrs, err
+}
+
+// addField adds the attribute of the field to the given attributes.
+// Fields with a sub attribute name (e.g. `scim:"name/givenName"`) are added to the complex attribute with that name.
+func addField(attrs []*schema.Attribute, t reflect.Type, tag tag) ([]*schema.Attribute, error) {
+	if tag.sub == nil {
+		attribute, err := inferAttribute(t, tag)
+		if err != nil {
+			return nil, err
+		}
+		if attributes.FindAttribute(tag.name, attrs) != nil {
+			return nil, fmt.Errorf("duplicate names: %s", tag.name)
+		}
+		return append(attrs, attribute), nil
+	}
+
+	parent := attributes.FindAttribute(tag.name, attrs)
+	if parent == nil {
+		parent = newAttribute(tag)
+		parent.Type = schema.ComplexType
+		attrs = append(attrs, parent)
+	}
+	if parent.Type != schema.ComplexType {
+		return nil, fmt.Errorf("%s is not a complex attribute", tag.name)
+	}
+	sub, err := inferAttribute(t, *tag.sub)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", tag.name, err)
+	}
+	if attributes.FindAttribute(sub.Name, parent.SubAttributes) != nil {
+		return nil, fmt.Errorf("duplicate names: %s", sub.Name)
+	}
+	parent.SubAttributes = append(parent.SubAttributes, sub)
+	return attrs, nil
+}
+
+// inferAttribute returns the attribute of a field of the given type.
+func inferAttribute(t reflect.Type, tag tag) (*schema.Attribute, error) {
+	attribute := newAttribute(tag)
+	t = derefType(t)
+	if tag.multiValued && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && !isBinary(t) {
+		t = derefType(t.Elem())
+	}
+
+	switch {
+	case implementsType(t, idMarshalerType):
+		attribute.Type = schema.StringType
+		return attribute, nil
+	case t == timeType:
+		attribute.Type = schema.DateTimeType
+		return attribute, nil
+	case implementsType(t, textMarshalerType) || implementsType(t, jsonMarshalerType) && t.Kind() != reflect.Struct:
+		// The JSON value of json.Marshalers is not known, most of them are strings. Structs are complex attributes.
+		attribute.Type = schema.StringType
+		return attribute, nil
+	case isBinary(t):
+		attribute.Type = schema.BinaryType
+		return attribute, nil
+	case t == numberType:
+		attribute.Type = schema.DecimalType
+		return attribute, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		attribute.Type = schema.BooleanType
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		attribute.Type = schema.IntegerType
+	case reflect.Float32, reflect.Float64:
+		attribute.Type = schema.DecimalType
+	case reflect.String:
+		attribute.Type = schema.StringType
+	case reflect.Map:
+		// The keys of maps, the sub attributes they are encoded as, are not known.
+		return nil, fmt.Errorf("can not infer the sub attributes of map %s", tag.name)
+	case reflect.Struct:
+		attribute.Type = schema.ComplexType
+		subAttributes, err := inferAttributes(t)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", tag.name, err)
+		}
+		attribute.SubAttributes = subAttributes
+	default:
+		return nil, fmt.Errorf("can not infer the type of %s: %s", tag.name, t)
+	}
+	return attribute, nil
+}
+
+func newAttribute(tag tag) *schema.Attribute {
+	attribute := &schema.Attribute{
+		Name:        tag.name,
+		MultiValued: tag.multiValued,
+		Description: tag.description,
+		Required:    tag.required,
+		CaseExact:   tag.caseExact,
+		Mutability:  tag.mutability,
+		Returned:    tag.returned,
+	}
+	attribute.Normalize()
+	return attribute
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func isCommonAttribute(name string) bool {
+	for _, attribute := range schema.CoreAttributes {
+		if strings.EqualFold(attribute.Name, name) {
+			return true
+		}
+	}
+	return false
+}
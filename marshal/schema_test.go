@@ -0,0 +1,133 @@
+package marshal
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/memsql/scimtools/schema"
+	"github.com/memsql/scimtools/validate"
+)
+
+type inferEmail struct {
+	Value   string `scim:",required"`
+	Type    string
+	Primary bool
+}
+
+type inferEnterpriseUser struct {
+	EmployeeNumber string
+	Manager        string `scim:"manager/value"`
+}
+
+type inferUser struct {
+	ID         string
+	UserName   string       `scim:",required,description=Unique identifier for the User."`
+	Password   string       `scim:",mutability=writeOnly,returned=never"`
+	GivenName  string       `scim:"name/givenName,required,_caseExact"`
+	FamilyName string       `scim:"name/familyName"`
+	Active     bool         `scim:",zero"`
+	Age        int          `scim:",mutability=readOnly"`
+	Emails     []inferEmail `scim:",mV"`
+	Nicknames  []string     `scim:"nickNames,mV"`
+	Created    time.Time    `scim:",mutability=readOnly"`
+	Photo      []byte
+	Balance    json.Number
+	Internal   string              `scim:",ignore"`
+	Enterprise inferEnterpriseUser `scim:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`
+}
+
+func ExampleInferSchema() {
+	type Name struct {
+		GivenName  string
+		FamilyName string `scim:",required"`
+	}
+	type User struct {
+		UserName string   `scim:",required,caseExact,description=Unique identifier for the User."`
+		Name     Name     `scim:",mutability=immutable"`
+		Emails   []string `scim:",mV,returned=always"`
+	}
+
+	s, _, _ := InferSchema("urn:ietf:params:scim:schemas:core:2.0:User", "User", User{})
+	raw, _ := json.Marshal(s.Attributes[0])
+	fmt.Println(string(raw))
+	for _, attribute := range s.Attributes {
+		fmt.Println(attribute.Name, attribute.Type, attribute.MultiValued, attribute.Mutability, attribute.Returned)
+	}
+
+	// Output:
+	// {"name":"userName","type":"string","multiValued":false,"description":"Unique identifier for the User.","required":true,"caseExact":true,"mutability":"readWrite","returned":"default","uniqueness":"none","referenceTypes":null}
+	// userName string false readWrite default
+	// name complex false immutable default
+	// emails string true readWrite always
+}
+
+func TestInferSchema(t *testing.T) {
+	s, extensions, err := InferSchema("urn:ietf:params:scim:schemas:core:2.0:User", "User", &inferUser{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, attribute := range s.Attributes {
+		names = append(names, attribute.Name)
+	}
+	if fmt.Sprint(names) != "[userName password name active age emails nickNames created photo balance]" {
+		t.Errorf("unexpected attributes %v", names)
+	}
+	name := s.Attributes[2]
+	if name.Type != "complex" || !name.Required || len(name.SubAttributes) != 2 || !name.SubAttributes[0].CaseExact {
+		t.Errorf("unexpected name attribute %+v", name)
+	}
+	for i, typ := range []schema.Type{schema.DateTimeType, schema.BinaryType, schema.DecimalType} {
+		if attribute := s.Attributes[7+i]; attribute.Type != typ {
+			t.Errorf("unexpected type of %s: %s", attribute.Name, attribute.Type)
+		}
+	}
+	if len(extensions) != 1 || extensions[0].Name != "inferEnterpriseUser" || len(extensions[0].Attributes) != 2 {
+		t.Errorf("unexpected extensions %+v", extensions)
+	}
+
+	// The marshalled resource must be valid according to the inferred schema.
+	resource, err := Marshal(inferUser{
+		ID:         "2819c223",
+		UserName:   "bjensen",
+		Password:   "secret",
+		GivenName:  "Barbara",
+		FamilyName: "Jensen",
+		Age:        42,
+		Emails:     []inferEmail{{Value: "bjensen@example.com", Type: "work", Primary: true}},
+		Nicknames:  []string{"Babs"},
+		Created:    time.Date(2010, 1, 23, 4, 56, 22, 0, time.UTC),
+		Photo:      []byte{0xff, 0xd8},
+		Balance:    "12.50",
+		Enterprise: inferEnterpriseUser{EmployeeNumber: "701984", Manager: "26118915"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validate.Validate(resource, s, extensions...); err != nil {
+		t.Errorf("%v: %s", resource, err)
+	}
+}
+
+func TestInferSchema_invalid(t *testing.T) {
+	for _, value := range []interface{}{
+		"",
+		struct{ Slice []string }{},
+		struct{ Any interface{} }{},
+		struct{ Labels map[string]string }{},
+		struct {
+			A string `scim:"a"`
+			B string `scim:"A"`
+		}{},
+		struct {
+			A string `scim:",mutability=writeOnce"`
+		}{},
+	} {
+		if _, _, err := InferSchema("urn:example", "Example", value); err == nil {
+			t.Errorf("%T: error expected, got none", value)
+		}
+	}
+}
@@ -0,0 +1,730 @@
+package marshal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/memsql/scimtools/validate"
+)
+
+// Encoder writes resources as JSON to an output stream, without building the intermediate map of Marshal.
+// Attributes are written in the order of the fields of the struct, the keys of maps are sorted. The same value always
+// results in the same output.
+type Encoder struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the JSON encoding of the value followed by a newline. The value is encoded as described by Marshal,
+// the resource is only written if it could be encoded completely.
+func (e *Encoder) Encode(value interface{}) error {
+	s := encodeState{buf: e.buf[:0]}
+	if err := s.resource(reflect.ValueOf(value)); err != nil {
+		return err
+	}
+	s.buf = append(s.buf, '\n')
+	e.buf = s.buf
+	_, err := e.w.Write(s.buf)
+	return err
+}
+
+// Decoder reads resources as JSON from an input stream, it is the inverse of the Encoder. Unlike the Encoder, it does
+// not bypass the intermediate map: every resource is read token by token into a map, and then decoded as described by
+// Unmarshal.
+type Decoder struct {
+	d      *json.Decoder
+	strict bool
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	return &Decoder{d: d}
+}
+
+// Strict makes the decoder report attributes that are not mapped to any field, see UnmarshalStrict.
+func (d *Decoder) Strict() *Decoder {
+	d.strict = true
+	return d
+}
+
+// Decode reads the next resource from the stream and stores it in the struct the value points to, as described by
+// Unmarshal. Numbers are decoded as json.Number, keys that occur multiple times within the same object are reported.
+func (d *Decoder) Decode(value interface{}) error {
+	t, err := d.d.Token()
+	if err != nil {
+		return err
+	}
+	if t != json.Delim('{') {
+		return fmt.Errorf("expected a resource, got %v", t)
+	}
+	var errs validate.Errors
+	resource, err := d.object("", &errs)
+	if err != nil {
+		return err
+	}
+	if len(errs) != 0 {
+		sort.SliceStable(errs, func(i, j int) bool {
+			return errs[i].Path < errs[j].Path
+		})
+		return errs
+	}
+	if d.strict {
+		return UnmarshalStrict(resource, value)
+	}
+	return Unmarshal(resource, value)
+}
+
+// object reads the members of an object of which the opening delimiter is already read.
+func (d *Decoder) object(path string, errs *validate.Errors) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for d.d.More() {
+		t, err := d.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := t.(string)
+		keyPath := path + key
+		if _, ok := m[key]; ok {
+			*errs = append(*errs, &validate.Error{
+				ScimType: validate.InvalidSyntax,
+				Path:     keyPath,
+				Detail:   "duplicate key",
+			})
+		}
+		separator := "."
+		if path == "" && strings.Contains(key, ":") {
+			// The sub attributes of extensions are separated by a colon.
+			separator = ":"
+		}
+		if m[key], err = d.value(keyPath+separator, errs); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := d.d.Token(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// value reads the next value, the path is the prefix of the attributes of objects within the value.
+func (d *Decoder) value(path string, errs *validate.Errors) (interface{}, error) {
+	t, err := d.d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case json.Delim('{'):
+		return d.object(path, errs)
+	case json.Delim('['):
+		values := make([]interface{}, 0)
+		for i := 0; d.d.More(); i++ {
+			value, err := d.value(fmt.Sprintf("%s[%d].", strings.TrimSuffix(path, "."), i), errs)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		if _, err := d.d.Token(); err != nil {
+			return nil, err
+		}
+		return values, nil
+	}
+	return t, nil
+}
+
+// errFallback is returned by the direct encoders if the value can not be written directly.
+var errFallback = errors.New("fallback")
+
+// encodeState writes the JSON encoding of a single resource.
+type encodeState struct {
+	buf []byte
+	// keys is the number of keys that are written, used to tell whether an extension has any attributes.
+	keys int
+}
+
+// mark is the state of the encoder before writing a value, to return to if the value is not written after all.
+type mark struct {
+	buf, keys int
+}
+
+func (s *encodeState) mark() mark {
+	return mark{buf: len(s.buf), keys: s.keys}
+}
+
+func (s *encodeState) reset(m mark) {
+	s.buf, s.keys = s.buf[:m.buf], m.keys
+}
+
+// entry is a field of a struct that is encoded.
+type entry struct {
+	field *fieldPlan
+	value reflect.Value
+}
+
+func (s *encodeState) resource(v reflect.Value) error {
+	if !v.IsValid() {
+		return errors.New("value is invalid")
+	}
+	if v.Type().Implements(marshalerType) {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return errors.New("ptr is nil")
+		}
+		resource, err := v.Interface().(Marshaler).MarshalSCIM()
+		if err != nil {
+			return err
+		}
+		return s.value(resource)
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return errors.New("interface is nil")
+		}
+		return s.resource(v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return errors.New("ptr is nil")
+		}
+		return s.resource(v.Elem())
+	case reflect.Struct:
+		return s.object(addressable(v), planOf(v.Type()).groups, true)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+}
+
+// object writes the fields of the struct as an object. The top level object also contains the schemas of its
+// extensions, extensions without attributes are not written.
+func (s *encodeState) object(v reflect.Value, groups []fieldGroup, top bool) error {
+	var (
+		scratch    [4]entry
+		schemas    = -1
+		extensions map[string][]byte
+		urns       []string
+	)
+	if top {
+		for i, group := range groups {
+			entries := group.entries(scratch[:0], v)
+			if len(entries) == 0 {
+				continue
+			}
+			tag := entries[0].field.tag
+			switch {
+			case strings.EqualFold(tag.name, "schemas") && tag.sub == nil:
+				schemas = i
+			case tag.extension():
+				e := encodeState{buf: []byte{'{'}}
+				if err := e.attribute(entries); err != nil {
+					return err
+				}
+				if extensions == nil {
+					extensions = make(map[string][]byte)
+				}
+				// Only the key of the extension itself is written if it has no attributes.
+				if e.keys <= 1 {
+					extensions[tag.name] = nil
+					continue
+				}
+				extensions[tag.name] = e.buf[1:]
+				urns = append(urns, tag.name)
+			}
+		}
+	}
+
+	s.buf = append(s.buf, '{')
+	for i, group := range groups {
+		entries := group.entries(scratch[:0], v)
+		if len(entries) == 0 {
+			continue
+		}
+		if tag := entries[0].field.tag; top {
+			if raw, ok := extensions[tag.name]; ok && tag.extension() {
+				if raw != nil {
+					s.separator()
+					s.buf = append(s.buf, raw...)
+				}
+				continue
+			}
+			if i == schemas && len(urns) != 0 {
+				if err := s.schemas(entries, urns); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if err := s.attribute(entries); err != nil {
+			return err
+		}
+	}
+	s.buf = append(s.buf, '}')
+	return nil
+}
+
+// entries appends the fields of the group that are encoded to the given entries.
+func (g fieldGroup) entries(entries []entry, v reflect.Value) []entry {
+fields:
+	for _, f := range g {
+		for _, index := range f.embedded {
+			if v.FieldByIndex(index).IsZero() {
+				continue fields
+			}
+		}
+		value := v.FieldByIndex(f.index)
+		if !f.tag.allowZero && value.IsZero() {
+			continue
+		}
+		entries = append(entries, entry{field: f.fieldPlan, value: value})
+	}
+	return entries
+}
+
+// schemas writes the schemas attribute of the group with the URNs of the extensions, as described by
+// addExtensionSchemas.
+func (s *encodeState) schemas(group []entry, urns []string) error {
+	resource := make(map[string]interface{})
+	for _, e := range group {
+		if err := structEncoder(resource, e.value, e.field.tag); err != nil {
+			return err
+		}
+	}
+	for key, value := range resource {
+		s.key(key)
+		return s.value(extensionSchemas(value, urns))
+	}
+	return nil
+}
+
+// attribute writes the attribute of the fields of the group. Attributes of a single field are written directly, the
+// others (e.g. complex attributes of which the sub attributes are separate fields) are encoded as described by Marshal.
+func (s *encodeState) attribute(group []entry) error {
+	m := s.mark()
+	if e := group[0]; len(group) == 1 && e.field.tag.sub == nil && len(e.field.tag.indexes) == 0 {
+		s.key(e.field.tag.name)
+		var (
+			ok  bool
+			err error
+		)
+		if e.field.tag.multiValued {
+			ok, err = s.multiValued(e.value)
+		} else {
+			ok, err = s.simple(e.value)
+		}
+		if err != errFallback {
+			if !ok {
+				s.reset(m)
+			}
+			return err
+		}
+		s.reset(m)
+	}
+
+	resource := make(map[string]interface{})
+	var subs []string
+	for _, e := range group {
+		if err := structEncoder(resource, e.value, e.field.tag); err != nil {
+			return err
+		}
+		if e.field.tag.sub != nil {
+			subs = append(subs, e.field.tag.sub.name)
+		}
+	}
+	for _, k := range sortedKeys(resource) {
+		s.key(k)
+		if err := s.ordered(resource[k], subs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ordered writes the value of a (multi valued) complex attribute, the sub attributes are written in the given order.
+func (s *encodeState) ordered(value interface{}, order []string) error {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if value == nil {
+			break
+		}
+		written := make(map[string]bool, len(value))
+		s.buf = append(s.buf, '{')
+		for _, keys := range [][]string{order, sortedKeys(value)} {
+			for _, k := range keys {
+				v, ok := value[k]
+				if !ok || written[k] {
+					continue
+				}
+				written[k] = true
+				s.key(k)
+				if err := s.value(v); err != nil {
+					return err
+				}
+			}
+		}
+		s.buf = append(s.buf, '}')
+		return nil
+	case []map[string]interface{}:
+		s.buf = append(s.buf, '[')
+		for _, v := range value {
+			s.separator()
+			if err := s.ordered(v, order); err != nil {
+				return err
+			}
+		}
+		s.buf = append(s.buf, ']')
+		return nil
+	}
+	return s.value(value)
+}
+
+// simple writes the value of a simple (or complex) attribute, as described by structEncoderSimple. Returns false if
+// nothing is written.
+func (s *encodeState) simple(v reflect.Value) (bool, error) {
+	if !v.IsValid() {
+		return false, nil
+	}
+	p := planOf(v.Type())
+	if p.receivers[idMarshalerType] == valueReceiver {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return false, errors.New("ptr is nil")
+		}
+		id, err := v.Interface().(IDMarshaler).MarshalSCIMUUID()
+		if err != nil {
+			return false, errors.New("fail to Marshal uuid")
+		}
+		s.string(id)
+		return true, nil
+	}
+	if p.scalar || p.receivers[jsonMarshalerType] != noReceiver || v.Type() == numberType {
+		value, ok, err := scalarAttribute(v)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			if value == nil {
+				return false, nil
+			}
+			return true, s.value(value)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		s.buf = strconv.AppendBool(s.buf, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s.buf = strconv.AppendInt(s.buf, v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.buf = strconv.AppendUint(s.buf, v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		// Marshal encodes all floats as float64.
+		if err := s.float(v.Float(), 64); err != nil {
+			return false, err
+		}
+	case reflect.String:
+		s.string(v.String())
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return false, errors.New("key of map is not a string")
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		s.buf = append(s.buf, '{')
+		for i, k := range keys {
+			for _, previous := range keys[:i] {
+				if strings.EqualFold(previous.String(), k.String()) {
+					return false, fmt.Errorf("duplicate keys: %s and %s", previous, k)
+				}
+			}
+			value := v.MapIndex(k)
+			for value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+				value = value.Elem()
+			}
+			attribute, err := validSimpleAttribute(value)
+			if err != nil {
+				return false, err
+			}
+			s.key(k.String())
+			if err := s.value(attribute); err != nil {
+				return false, err
+			}
+		}
+		s.buf = append(s.buf, '}')
+	case reflect.Ptr, reflect.Interface:
+		return s.simple(v.Elem())
+	case reflect.Struct:
+		if err := s.object(v, planOf(v.Type()).groups, false); err != nil {
+			return false, err
+		}
+	case reflect.Array, reflect.Slice:
+		return false, fmt.Errorf("invalid simple attribute: %s", v.Kind())
+	default:
+		attribute, err := validSimpleAttribute(v)
+		if err != nil {
+			return false, err
+		}
+		if err := s.value(attribute); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// multiValued writes the values of a multi valued attribute, as described by structEncoderSimpleMultiValued.
+// Returns false if nothing is written, errFallback if the values can not be written directly.
+func (s *encodeState) multiValued(v reflect.Value) (bool, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false, errFallback
+		}
+		v = v.Elem()
+	}
+	if isScalar(v.Type()) {
+		s.buf = append(s.buf, '[')
+		if ok, err := s.simple(v); !ok || err != nil {
+			return false, errFallback
+		}
+		s.buf = append(s.buf, ']')
+		return true, nil
+	}
+	if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() == reflect.Interface {
+		return false, errFallback
+	}
+
+	s.buf = append(s.buf, '[')
+	var n int
+	for i := 0; i < v.Len(); i++ {
+		m := s.mark()
+		s.separator()
+		ok, err := s.simple(v.Index(i))
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			s.reset(m)
+			continue
+		}
+		n++
+	}
+	s.buf = append(s.buf, ']')
+	return n != 0, nil
+}
+
+// value writes a value of a resource produced by Marshal.
+func (s *encodeState) value(value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		s.buf = append(s.buf, "null"...)
+	case string:
+		s.string(value)
+	case bool:
+		s.buf = strconv.AppendBool(s.buf, value)
+	case json.Number:
+		if value == "" {
+			value = "0"
+		}
+		s.buf = append(s.buf, value...)
+	case int64:
+		s.buf = strconv.AppendInt(s.buf, value, 10)
+	case uint64:
+		s.buf = strconv.AppendUint(s.buf, value, 10)
+	case float64:
+		return s.float(value, 64)
+	case map[string]interface{}:
+		if value == nil {
+			s.buf = append(s.buf, "null"...)
+			return nil
+		}
+		s.buf = append(s.buf, '{')
+		for _, k := range sortedKeys(value) {
+			s.key(k)
+			if err := s.value(value[k]); err != nil {
+				return err
+			}
+		}
+		s.buf = append(s.buf, '}')
+	case []map[string]interface{}:
+		s.buf = append(s.buf, '[')
+		for _, v := range value {
+			s.separator()
+			if err := s.value(v); err != nil {
+				return err
+			}
+		}
+		s.buf = append(s.buf, ']')
+	case []interface{}:
+		s.buf = append(s.buf, '[')
+		for _, v := range value {
+			s.separator()
+			if err := s.value(v); err != nil {
+				return err
+			}
+		}
+		s.buf = append(s.buf, ']')
+	default:
+		return s.reflectValue(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+// reflectValue writes values of other types, e.g. the resources of Marshalers.
+func (s *encodeState) reflectValue(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Invalid:
+		s.buf = append(s.buf, "null"...)
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			s.buf = append(s.buf, "null"...)
+			return nil
+		}
+		return s.reflectValue(v.Elem())
+	case reflect.Bool:
+		s.buf = strconv.AppendBool(s.buf, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s.buf = strconv.AppendInt(s.buf, v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.buf = strconv.AppendUint(s.buf, v.Uint(), 10)
+	case reflect.Float32:
+		return s.float(v.Float(), 32)
+	case reflect.Float64:
+		return s.float(v.Float(), 64)
+	case reflect.String:
+		s.string(v.String())
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("key of map is not a string")
+		}
+		if v.IsNil() {
+			s.buf = append(s.buf, "null"...)
+			return nil
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		s.buf = append(s.buf, '{')
+		for _, k := range keys {
+			s.key(k.String())
+			if err := s.reflectValue(v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+		s.buf = append(s.buf, '}')
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			s.buf = append(s.buf, "null"...)
+			return nil
+		}
+		s.buf = append(s.buf, '[')
+		for i := 0; i < v.Len(); i++ {
+			s.separator()
+			if err := s.reflectValue(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		s.buf = append(s.buf, ']')
+	default:
+		return fmt.Errorf("unsupported value of type %s", v.Type())
+	}
+	return nil
+}
+
+// separator writes a comma if the current object or array already contains a value.
+func (s *encodeState) separator() {
+	if n := len(s.buf); n != 0 && s.buf[n-1] != '{' && s.buf[n-1] != '[' {
+		s.buf = append(s.buf, ',')
+	}
+}
+
+func (s *encodeState) key(key string) {
+	s.keys++
+	s.separator()
+	s.string(key)
+	s.buf = append(s.buf, ':')
+}
+
+// float writes the number in the same format as encoding/json.
+func (s *encodeState) float(f float64, bits int) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return fmt.Errorf("unsupported value: %s", strconv.FormatFloat(f, 'g', -1, bits))
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	s.buf = strconv.AppendFloat(s.buf, f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		if n := len(s.buf); n >= 4 && s.buf[n-4] == 'e' && s.buf[n-3] == '-' && s.buf[n-2] == '0' {
+			s.buf[n-2] = s.buf[n-1]
+			s.buf = s.buf[:n-1]
+		}
+	}
+	return nil
+}
+
+const hex = "0123456789abcdef"
+
+// string writes the string as a JSON string, escaped the same way as encoding/json (including HTML characters).
+func (s *encodeState) string(str string) {
+	s.buf = append(s.buf, '"')
+	start := 0
+	for i := 0; i < len(str); {
+		if c := str[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			s.buf = append(s.buf, str[start:i]...)
+			switch c {
+			case '"', '\\':
+				s.buf = append(s.buf, '\\', c)
+			case '\n':
+				s.buf = append(s.buf, '\\', 'n')
+			case '\r':
+				s.buf = append(s.buf, '\\', 'r')
+			case '\t':
+				s.buf = append(s.buf, '\\', 't')
+			default:
+				s.buf = append(s.buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(str[i:])
+		if r == utf8.RuneError && size == 1 {
+			s.buf = append(s.buf, str[start:i]...)
+			s.buf = append(s.buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			s.buf = append(s.buf, str[start:i]...)
+			s.buf = append(s.buf, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	s.buf = append(s.buf, str[start:]...)
+	s.buf = append(s.buf, '"')
+}
@@ -0,0 +1,322 @@
+package marshal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	fuzz "github.com/google/gofuzz"
+	"github.com/memsql/scimtools/validate"
+)
+
+func ExampleEncoder() {
+	type User struct {
+		UserName   string
+		GivenName  string   `scim:"name/givenName"`
+		FamilyName string   `scim:"name/familyName"`
+		Active     bool     `scim:",zero"`
+		Emails     []string `scim:",mV"`
+	}
+
+	_ = NewEncoder(os.Stdout).Encode(User{
+		UserName:   "di-wu",
+		GivenName:  "Quint",
+		FamilyName: "Daenen",
+		Emails:     []string{"quint@example.com"},
+	})
+	// Output:
+	// {"userName":"di-wu","name":{"givenName":"Quint","familyName":"Daenen"},"active":false,"emails":["quint@example.com"]}
+}
+
+func ExampleDecoder() {
+	type User struct {
+		UserName string
+		Age      int
+	}
+
+	d := NewDecoder(strings.NewReader(`{"userName":"di-wu","age":27} {"userName":"bjensen"}`))
+	for {
+		var user User
+		if err := d.Decode(&user); err != nil {
+			break
+		}
+		fmt.Println(user)
+	}
+	// Output:
+	// {di-wu 27}
+	// {bjensen 0}
+}
+
+func TestEncoder_extensions(t *testing.T) {
+	type enterprise struct {
+		EmployeeNumber string
+	}
+	type user struct {
+		UserName   string
+		Schemas    []string    `scim:",mV"`
+		Enterprise *enterprise `scim:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`
+	}
+
+	for _, test := range []struct {
+		value  user
+		output string
+	}{
+		{
+			value:  user{UserName: "di-wu"},
+			output: `{"userName":"di-wu"}`,
+		},
+		{
+			value:  user{UserName: "di-wu", Enterprise: &enterprise{}},
+			output: `{"userName":"di-wu"}`,
+		},
+		{
+			value:  user{UserName: "di-wu", Schemas: []string{"urn:ietf:params:scim:schemas:core:2.0:User"}},
+			output: `{"userName":"di-wu","schemas":["urn:ietf:params:scim:schemas:core:2.0:User"]}`,
+		},
+		{
+			value: user{
+				UserName:   "di-wu",
+				Schemas:    []string{"urn:ietf:params:scim:schemas:core:2.0:User"},
+				Enterprise: &enterprise{EmployeeNumber: "701984"},
+			},
+			output: `{"userName":"di-wu","schemas":["urn:ietf:params:scim:schemas:core:2.0:User","urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"employeeNumber":"701984"}}`,
+		},
+		{
+			value:  user{Enterprise: &enterprise{EmployeeNumber: "701984"}},
+			output: `{"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"employeeNumber":"701984"}}`,
+		},
+	} {
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf).Encode(test.value); err != nil {
+			t.Fatal(err)
+		}
+		if output := strings.TrimSuffix(buf.String(), "\n"); output != test.output {
+			t.Errorf("expected %s, got %s", test.output, output)
+		}
+	}
+}
+
+func TestEncoder_invalid(t *testing.T) {
+	for _, value := range []interface{}{
+		nil,
+		"di-wu",
+		struct{ Values [][]string }{Values: [][]string{{"a"}}},
+	} {
+		var buf bytes.Buffer
+		_, marshalErr := Marshal(value)
+		err := NewEncoder(&buf).Encode(value)
+		if err == nil || marshalErr == nil {
+			t.Errorf("expected an error for %#v: %v, %v", value, err, marshalErr)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("expected nothing to be written, got %s", buf.String())
+		}
+	}
+}
+
+func TestEncoder_roundTrip(t *testing.T) {
+	f := fuzz.New().NilChance(.3).NumElements(1, 3).Funcs(func(t *time.Time, c fuzz.Continue) {
+		*t = time.Unix(c.Int63n(1<<33), c.Int63n(1e9)).UTC()
+	}, func(b *[]byte, c fuzz.Continue) {
+		*b = []byte(c.RandString())
+	})
+	for i := 0; i < 1000; i++ {
+		var user roundTripUser
+		f.Fuzz(&user)
+		user.Internal = ""
+		if user.Enterprise != nil && *user.Enterprise == (roundTripEnterprise{}) {
+			user.Enterprise = nil
+		}
+
+		var buf bytes.Buffer
+		e := NewEncoder(&buf)
+		if err := e.Encode(user); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.Encode(&user); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.SplitAfter(buf.String(), "\n")
+		if lines[0] != lines[1] {
+			t.Fatalf("output is not deterministic:\n%s%s", lines[0], lines[1])
+		}
+
+		// The output must be the same as the JSON encoding of the map of Marshal.
+		resource, err := Marshal(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := json.Marshal(resource)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expected, actual interface{}
+		if err := json.Unmarshal(raw, &expected); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(lines[0]), &actual); err != nil {
+			t.Fatalf("%s: %v", lines[0], err)
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("output does not match Marshal:\n%s\n%s", raw, lines[0])
+		}
+
+		d := NewDecoder(&buf).Strict()
+		for j := 0; j < 2; j++ {
+			var decoded roundTripUser
+			if err := d.Decode(&decoded); err != nil {
+				t.Fatalf("%s: %v", lines[j], err)
+			}
+			if !reflect.DeepEqual(user, decoded) {
+				t.Fatalf("round trip failed:\n%#v\n%#v\n%s", user, decoded, lines[j])
+			}
+		}
+	}
+}
+
+// fuzzInterface returns a random value for interface{} fields, the keys of maps (see fuzzMap) only differ in case now
+// and then.
+func fuzzInterface(c fuzz.Continue, depth int) interface{} {
+	switch n := c.Intn(9); {
+	case n == 0:
+		return nil
+	case n == 1:
+		return c.RandString()
+	case n == 2:
+		return c.RandBool()
+	case n == 3:
+		return c.Int63()
+	case n == 4:
+		return c.Float64()
+	case n == 5:
+		return json.Number(strconv.Itoa(c.Int()))
+	case n == 6:
+		return time.Unix(c.Int63n(1<<33), 0).UTC()
+	case n == 7 && depth < 2:
+		return []interface{}{fuzzInterface(c, depth+1)}
+	case depth < 2:
+		return fuzzMap(c, depth+1)
+	}
+	return nil
+}
+
+func fuzzMap(c fuzz.Continue, depth int) map[string]interface{} {
+	m := make(map[string]interface{})
+	for i := c.Intn(3); i >= 0; i-- {
+		m[[]string{"a", "A", "b", "c"}[c.Intn(4)]] = fuzzInterface(c, depth)
+	}
+	return m
+}
+
+func TestEncoder_marshal(t *testing.T) {
+	type maps struct {
+		Attributes map[string]interface{}
+		Labels     map[string]string
+		Value      interface{}
+		Values     []interface{} `scim:",mV"`
+		Zero       interface{}   `scim:",zero"`
+	}
+
+	f := fuzz.New().NilChance(.2).NumElements(1, 3).Funcs(func(v *interface{}, c fuzz.Continue) {
+		*v = fuzzInterface(c, 0)
+	}, func(m *map[string]interface{}, c fuzz.Continue) {
+		*m = fuzzMap(c, 1)
+	}, func(m *map[string]string, c fuzz.Continue) {
+		*m = map[string]string{[]string{"n", "N"}[c.Intn(2)]: c.RandString(), "m": c.RandString()}
+	})
+	var failures int
+	for i := 0; i < 1000; i++ {
+		var value maps
+		f.Fuzz(&value)
+
+		var buf bytes.Buffer
+		err := NewEncoder(&buf).Encode(value)
+		resource, marshalErr := Marshal(value)
+		if (err == nil) != (marshalErr == nil) {
+			t.Fatalf("%#v: expected %v, got %v", value, marshalErr, err)
+		}
+		if err != nil {
+			failures++
+			continue
+		}
+
+		raw, err := json.Marshal(resource)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expected, actual interface{}
+		if err := json.Unmarshal(raw, &expected); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(buf.Bytes(), &actual); err != nil {
+			t.Fatalf("%s: %v", buf.String(), err)
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("output does not match Marshal:\n%s\n%s", raw, buf.String())
+		}
+	}
+	if failures == 0 || failures == 1000 {
+		t.Errorf("expected both valid and invalid values, got %d invalid values", failures)
+	}
+}
+
+func TestDecoder_errors(t *testing.T) {
+	d := NewDecoder(strings.NewReader(`{
+		"userName": "di-wu",
+		"userName": "bjensen",
+		"emails": [{"value": "a@example.com", "value": "b@example.com"}],
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "1", "employeeNumber": "2"}
+	}`))
+	var user roundTripUser
+	err := d.Decode(&user)
+	var errs validate.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	var paths []string
+	for _, err := range errs {
+		if err.ScimType != validate.InvalidSyntax {
+			t.Errorf("unexpected error type %q", err.ScimType)
+		}
+		paths = append(paths, err.Path)
+	}
+	expected := []string{
+		"emails[0].value",
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber",
+		"userName",
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+
+	for _, input := range []string{`["di-wu"]`, `null`, `{"userName": "di-wu"`, `{"userName": }`} {
+		var user roundTripUser
+		if err := NewDecoder(strings.NewReader(input)).Decode(&user); err == nil {
+			t.Errorf("expected an error for %s", input)
+		}
+	}
+
+	err = NewDecoder(strings.NewReader(`{"unknown": 1, "age": "1"}`)).Strict().Decode(&user)
+	if !errors.As(err, &errs) || len(errs) != 2 || errs[0].Path != "age" || errs[1].Path != "unknown" {
+		t.Errorf("expected errors for age and unknown, got %v", err)
+	}
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		if err := e.Encode(benchmarkValue); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+
+	"github.com/memsql/scimtools/schema"
 )
 
 type tag struct {
@@ -13,13 +15,20 @@ type tag struct {
 	allowZero   bool
 	ignore      bool
 	sub         *tag
+
+	// characteristics of the attribute, only used to infer schemas.
+	required    bool
+	caseExact   bool
+	mutability  schema.Mutability
+	returned    schema.Returned
+	description string
 }
 
 func parseTags(field reflect.StructField) tag {
 	var t tag
 
 	scimTag := field.Tag.Get("scim")
-	tags := strings.Split(scimTag, ",")
+	tags := splitTag(scimTag)
 	if scimTag == "" {
 		t.name = lowerFirstRune(field.Name)
 	} else {
@@ -47,6 +56,10 @@ func parseTags(field reflect.StructField) tag {
 				option = strings.TrimPrefix(option, "_")
 				sub = true
 			}
+			target := &t
+			if sub {
+				target = t.sub
+			}
 
 			switch option {
 			case "multiValued", "mV":
@@ -67,10 +80,23 @@ func parseTags(field reflect.StructField) tag {
 				} else {
 					t.sub.ignore = true
 				}
+			case "required":
+				target.required = true
+			case "caseExact":
+				target.caseExact = true
+			}
+
+			switch {
+			case strings.HasPrefix(option, "mutability="):
+				target.mutability = schema.Mutability(strings.TrimPrefix(option, "mutability="))
+			case strings.HasPrefix(option, "returned="):
+				target.returned = schema.Returned(strings.TrimPrefix(option, "returned="))
+			case strings.HasPrefix(option, "description="):
+				target.description = unquote(strings.TrimPrefix(option, "description="))
 			}
 
 			if strings.HasPrefix(option, "index=") || strings.HasPrefix(option, "i=") {
-				option = strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(option, "i"), "index"), "=")
+				option = option[strings.Index(option, "=")+1:]
 				if option == "all" {
 					if !sub {
 						t.indexes = []int{-1}
@@ -86,7 +112,7 @@ func parseTags(field reflect.StructField) tag {
 								if !sub {
 									t.indexes = append(t.indexes, i)
 								} else {
-									t.sub.indexes = append(t.indexes, i)
+									t.sub.indexes = append(t.sub.indexes, i)
 								}
 							}
 						}
@@ -107,7 +133,7 @@ func parseTags(field reflect.StructField) tag {
 							if !sub {
 								t.indexes = append(t.indexes, i)
 							} else {
-								t.sub.indexes = append(t.indexes, i)
+								t.sub.indexes = append(t.sub.indexes, i)
 							}
 						}
 					}
@@ -119,6 +145,43 @@ func parseTags(field reflect.StructField) tag {
 	return t
 }
 
+// splitTag splits the tag into its options. Values that contain commas can be quoted with single quotes, e.g.
+// `scim:",description='Unique identifier, assigned by the service provider.'"`. A value is quoted if it starts with a
+// quote, the quote that is followed by a comma (or the end of the tag) ends it.
+func splitTag(s string) []string {
+	var (
+		options []string
+		start   int
+		quoted  bool
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\'' && !quoted && i != 0 && s[i-1] == '=':
+			quoted = true
+		case s[i] == '\'' && quoted && (i == len(s)-1 || s[i+1] == ','):
+			quoted = false
+		case s[i] == ',' && !quoted:
+			options = append(options, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(options, s[start:])
+}
+
+// unquote removes the single quotes around a quoted value, see splitTag.
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// extension returns whether the tag names an extension of the resource, e.g.
+// `scim:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"`.
+func (t tag) extension() bool {
+	return strings.Contains(t.name, ":") && t.sub == nil
+}
+
 func (t tag) all() bool {
 	return len(t.indexes) == 1 && t.indexes[0] == -1
 }
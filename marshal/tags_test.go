@@ -10,12 +10,18 @@ func TestParseTags(t *testing.T) {
 		IgnoreJSON      string `json:"ignore"`
 		IgnoreOtherTags string `json:"ignoreOther" scim:"ignore"`
 		Colon           string `scim:"c:o.l.o:n"`
+		Quoted          string `scim:",description='Unique identifier, assigned by the service provider.',required"`
+		Apostrophe      string `scim:",description=The user's name,caseExact"`
+		SubQuoted       string `scim:"name/givenName,_description='Given name, or first name.'"`
 	}
 
 	tagRefs := []tag{
 		{name: "ignoreJSON"},
 		{name: "ignore"},
 		{name: "c:o.l.o:n"},
+		{name: "quoted", description: "Unique identifier, assigned by the service provider.", required: true},
+		{name: "apostrophe", description: "The user's name", caseExact: true},
+		{name: "name", sub: &tag{name: "givenName", description: "Given name, or first name."}},
 	}
 
 	v := reflect.TypeOf(_tags{})
@@ -0,0 +1,296 @@
+package patch
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/memsql/scimtools/attributes"
+	"github.com/memsql/scimtools/filter"
+	"github.com/memsql/scimtools/schema"
+	"github.com/memsql/scimtools/validate"
+)
+
+// Diff returns the operations that turn the old version of a resource into the new one, ordered by attribute name.
+// Applying the operations to the old version results in the new version, apart from read only attributes that are ignored.
+//
+// Values of multi valued complex attributes are targeted with value filters (e.g. `emails[value eq "bjensen@example.com"]`),
+// if no filter can uniquely identify a value the whole attribute is replaced.
+// Returns an error if either resource contains attributes that are not defined by the schemas.
+func Diff(old, new map[string]interface{}, s schema.ReferenceSchema, ext ...schema.ReferenceSchema) ([]Operation, error) {
+	p := patcher{s: s, ext: ext}
+	o := normalize(old).(map[string]interface{})
+	n := normalize(new).(map[string]interface{})
+
+	var operations []Operation
+	for _, key := range keys(o, n) {
+		ov, nv := o[attributes.ExistingKey(key, o)], n[attributes.ExistingKey(key, n)]
+		if e, ok := p.extension(key); ok {
+			om, ok := toComplex(ov)
+			if !ok {
+				return nil, errorf(validate.InvalidValue, key, "extension value must be complex")
+			}
+			nm, ok := toComplex(nv)
+			if !ok {
+				return nil, errorf(validate.InvalidValue, key, "extension value must be complex")
+			}
+			for _, k := range keys(om, nm) {
+				t, err := p.resolve(filter.Path{AttributePath: filter.AttributePath{AttributeName: k}}, &e)
+				if err != nil {
+					return nil, err
+				}
+				operations = append(operations, p.diff(t, om[attributes.ExistingKey(k, om)], nm[attributes.ExistingKey(k, nm)])...)
+			}
+			continue
+		}
+
+		t, err := p.resolve(filter.Path{AttributePath: filter.AttributePath{AttributeName: key}}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if t.attribute == schema.SchemasAttribute {
+			// The schemas are updated when extensions are added or removed.
+			continue
+		}
+		operations = append(operations, p.diff(t, ov, nv)...)
+	}
+	return operations, nil
+}
+
+// diff returns the operations that change the value of the attribute.
+func (p *patcher) diff(t target, old, new interface{}) []Operation {
+	if t.attribute.Mutability == schema.ReadOnly {
+		return nil
+	}
+	old, new = writable(t.attribute, old), writable(t.attribute, new)
+	path := t.String()
+	switch {
+	case reflect.DeepEqual(old, new):
+		return nil
+	case empty(new):
+		return []Operation{{Op: Remove, Path: path}}
+	case empty(old):
+		return []Operation{{Op: Add, Path: path, Value: new}}
+	case t.attribute.MultiValued:
+		var operations []Operation
+		if t.attribute.Type == schema.ComplexType {
+			operations = p.diffValues(t, attributes.ToSlice(old), attributes.ToSlice(new))
+		} else if ov, nv := attributes.ToSlice(old), attributes.ToSlice(new); len(nv) > len(ov) {
+			operations = []Operation{{Op: Add, Path: path, Value: nv[len(ov):]}}
+		}
+		if operations == nil || !p.verify(t, old, new, operations) {
+			return []Operation{{Op: Replace, Path: path, Value: new}}
+		}
+		return operations
+	case t.attribute.Type == schema.ComplexType:
+		om, ok := toComplex(old)
+		nm, ok2 := toComplex(new)
+		if !ok || !ok2 {
+			return []Operation{{Op: Replace, Path: path, Value: new}}
+		}
+		var operations []Operation
+		for _, k := range keys(om, nm) {
+			sub := attributes.FindAttribute(k, t.attribute.SubAttributes)
+			if sub == nil {
+				return []Operation{{Op: Replace, Path: path, Value: new}}
+			}
+			ov, nv := om[attributes.ExistingKey(k, om)], nm[attributes.ExistingKey(k, nm)]
+			switch st := t.withSub(sub); {
+			case reflect.DeepEqual(ov, nv):
+			case nv == nil:
+				operations = append(operations, Operation{Op: Remove, Path: st.String()})
+			default:
+				operations = append(operations, Operation{Op: Replace, Path: st.String(), Value: nv})
+			}
+		}
+		return operations
+	default:
+		return []Operation{{Op: Replace, Path: path, Value: new}}
+	}
+}
+
+// diffValues returns the operations that change the values of a multi valued complex attribute, nil if the
+// values can not be targeted by value filters. Changed values are replaced, values that are no longer present
+// are removed and new values are added at the end.
+func (p *patcher) diffValues(t target, old, new []interface{}) []Operation {
+	var operations []Operation
+	used := make([]bool, len(new))
+	for i, o := range old {
+		if j := unused(new, used, func(n interface{}) bool { return reflect.DeepEqual(o, n) }); j != -1 {
+			used[j] = true
+			continue
+		}
+
+		ft := t
+		if ft.filter = elementFilter(t, old, i); ft.filter == nil {
+			return nil
+		}
+		ft.path.ValueFilter = ft.filter
+		j := unused(new, used, func(n interface{}) bool {
+			indexes, _ := ft.match([]interface{}{n})
+			return len(indexes) == 1
+		})
+		if j == -1 {
+			operations = append(operations, Operation{Op: Remove, Path: ft.String()})
+			continue
+		}
+		used[j] = true
+		operations = append(operations, Operation{Op: Replace, Path: ft.String(), Value: new[j]})
+	}
+
+	var added []interface{}
+	for j, n := range new {
+		if !used[j] {
+			added = append(added, n)
+		}
+	}
+	if len(added) != 0 {
+		operations = append(operations, Operation{Op: Add, Path: t.String(), Value: added})
+	}
+	return operations
+}
+
+// verify checks whether applying the operations to the old value of the attribute results in the new value.
+func (p *patcher) verify(t target, old, new interface{}, operations []Operation) bool {
+	resource := map[string]interface{}{t.attribute.Name: normalize(old)}
+	if t.ext != nil {
+		resource = map[string]interface{}{t.ext.ID: resource}
+	}
+	v := patcher{s: p.s, ext: p.ext, resource: resource}
+	for _, op := range operations {
+		if err := v.apply(op); err != nil {
+			return false
+		}
+	}
+	var value interface{}
+	if container := v.container(t, false); container != nil {
+		value = container[attributes.ExistingKey(t.attribute.Name, container)]
+	}
+	return reflect.DeepEqual(writable(t.attribute, value), new)
+}
+
+// elementFilter returns a value filter that only matches the value at the given index, nil if there is none.
+// The "value" sub attribute is preferred, otherwise all the string and boolean sub attributes are compared.
+func elementFilter(t target, values []interface{}, index int) filter.Expression {
+	e, ok := values[index].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var comparisons []filter.Expression
+	for _, sub := range t.attribute.SubAttributes {
+		if sub.MultiValued {
+			continue
+		}
+		switch sub.Type {
+		case schema.StringType, schema.ReferenceType, schema.BooleanType, "":
+		default:
+			continue
+		}
+		value, ok := e[attributes.ExistingKey(sub.Name, e)]
+		if !ok || value == nil {
+			continue
+		}
+		switch value.(type) {
+		case string, bool:
+		default:
+			continue
+		}
+		comparison := &filter.ComparisonExpression{
+			AttributePath: filter.AttributePath{AttributeName: sub.Name},
+			Operator:      filter.EQ,
+			CompareValue:  value,
+		}
+		if strings.EqualFold(sub.Name, "value") {
+			comparisons = append([]filter.Expression{comparison}, comparisons...)
+			continue
+		}
+		comparisons = append(comparisons, comparison)
+	}
+	if len(comparisons) == 0 {
+		return nil
+	}
+
+	unique := func(expr filter.Expression) bool {
+		ft := t
+		ft.filter = expr
+		indexes, err := ft.match(values)
+		return err == nil && len(indexes) == 1 && indexes[0] == index
+	}
+	if unique(comparisons[0]) {
+		return comparisons[0]
+	}
+	expr := comparisons[0]
+	for _, comparison := range comparisons[1:] {
+		expr = &filter.LogicalExpression{Operator: filter.AND, Left: expr, Right: comparison}
+	}
+	if unique(expr) {
+		return expr
+	}
+	return nil
+}
+
+// writable returns the value without its read only sub attributes.
+func writable(attribute *schema.Attribute, value interface{}) interface{} {
+	if attribute.Type != schema.ComplexType || value == nil {
+		return value
+	}
+	strip := func(v interface{}) interface{} {
+		e, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		m := make(map[string]interface{}, len(e))
+		for k, sv := range e {
+			if sub := attributes.FindAttribute(k, attribute.SubAttributes); sub != nil && sub.Mutability == schema.ReadOnly {
+				continue
+			}
+			m[k] = sv
+		}
+		return m
+	}
+	if !attribute.MultiValued {
+		return strip(value)
+	}
+	values := make([]interface{}, 0, len(attributes.ToSlice(value)))
+	for _, v := range attributes.ToSlice(value) {
+		values = append(values, strip(v))
+	}
+	return values
+}
+
+// unused returns the index of the first unused value that satisfies f, -1 if there is none.
+func unused(values []interface{}, used []bool, f func(v interface{}) bool) int {
+	for i, v := range values {
+		if !used[i] && f(v) {
+			return i
+		}
+	}
+	return -1
+}
+
+// keys returns the (case insensitive) unique keys of both maps in sorted order.
+func keys(a, b map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range []map[string]interface{}{a, b} {
+		for k := range m {
+			if !seen[strings.ToLower(k)] {
+				seen[strings.ToLower(k)] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.ToLower(keys[i]) < strings.ToLower(keys[j])
+	})
+	return keys
+}
+
+func toComplex(value interface{}) (map[string]interface{}, bool) {
+	if value == nil {
+		return nil, true
+	}
+	m, ok := value.(map[string]interface{})
+	return m, ok
+}
@@ -0,0 +1,140 @@
+package patch_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/memsql/scimtools/patch"
+)
+
+func ExampleDiff() {
+	old := newUser()
+	new := newUser()
+	new["nickName"] = "Babs"
+	new["name"] = map[string]interface{}{"givenName": "Barbara", "familyName": "Jensen"}
+	new["emails"] = []interface{}{
+		map[string]interface{}{"value": "bjensen@example.com", "type": "work", "primary": true},
+		map[string]interface{}{"value": "babs@example.com", "type": "home"},
+	}
+
+	operations, _ := patch.Diff(old, new, userSchema)
+	for _, op := range operations {
+		fmt.Println(op.Op, op.Path, op.Value)
+	}
+
+	// Output:
+	// remove emails[value eq "babs@jensen.org"] <nil>
+	// add emails [map[type:home value:babs@example.com]]
+	// replace name.familyName Jensen
+	// add nickName Babs
+}
+
+func TestDiff(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		change func(user map[string]interface{})
+		ops    int
+	}{
+		{
+			name:   "equal",
+			change: func(map[string]interface{}) {},
+		},
+		{
+			name: "attributes",
+			change: func(user map[string]interface{}) {
+				user["userName"] = "babs"
+				user["active"] = true
+				delete(user, "name")
+			},
+			ops: 3,
+		},
+		{
+			name: "replace value",
+			change: func(user map[string]interface{}) {
+				emails := user["emails"].([]interface{})
+				emails[1] = map[string]interface{}{"value": "babs@jensen.org", "type": "other"}
+			},
+			ops: 1,
+		},
+		{
+			name: "primary",
+			change: func(user map[string]interface{}) {
+				user["emails"] = []interface{}{
+					map[string]interface{}{"value": "bjensen@example.com", "type": "work", "primary": false},
+					map[string]interface{}{"value": "babs@jensen.org", "type": "home", "primary": true},
+				}
+			},
+			ops: 2,
+		},
+		{
+			name: "same value",
+			change: func(user map[string]interface{}) {
+				user["emails"] = []interface{}{
+					map[string]interface{}{"value": "babs@jensen.org", "type": "work"},
+					map[string]interface{}{"value": "babs@jensen.org", "type": "home"},
+				}
+			},
+			ops: 1,
+		},
+		{
+			name: "reorder",
+			change: func(user map[string]interface{}) {
+				emails := user["emails"].([]interface{})
+				emails[0], emails[1] = emails[1], emails[0]
+			},
+			ops: 1,
+		},
+		{
+			name: "read only",
+			change: func(user map[string]interface{}) {
+				user["id"] = "902c246b"
+				user["groups"] = []interface{}{map[string]interface{}{"value": "e9e30dba"}}
+			},
+		},
+		{
+			name: "extension",
+			change: func(user map[string]interface{}) {
+				user["schemas"] = []interface{}{userSchema.ID, enterpriseSchema.ID}
+				user[enterpriseSchema.ID] = map[string]interface{}{"employeeNumber": "701984"}
+			},
+			ops: 1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			old, new := newUser(), newUser()
+			test.change(new)
+
+			operations, err := patch.Diff(old, new, userSchema, enterpriseSchema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(operations) != test.ops {
+				t.Errorf("expected %d operations, got %v", test.ops, operations)
+			}
+
+			result, err := patch.Apply(old, operations, userSchema, enterpriseSchema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, k := range []string{"id", "groups"} {
+				if v, ok := old[k]; ok {
+					new[k] = v
+				} else {
+					delete(new, k)
+				}
+			}
+			if !reflect.DeepEqual(result, new) {
+				t.Errorf("expected %v, got %v", new, result)
+			}
+		})
+	}
+}
+
+func TestDiff_unknown(t *testing.T) {
+	new := newUser()
+	new["title"] = "Tour Guide"
+	if _, err := patch.Diff(newUser(), new, userSchema); err == nil {
+		t.Error("error expected, got none")
+	}
+}
@@ -0,0 +1,587 @@
+// Package patch applies SCIM PATCH operations (RFC 7644 §3.5.2) to resources.
+package patch
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/memsql/scimtools/attributes"
+	"github.com/memsql/scimtools/filter"
+	"github.com/memsql/scimtools/schema"
+	"github.com/memsql/scimtools/validate"
+)
+
+// PatchOpSchema is the URN of the schema of a PATCH request.
+const PatchOpSchema = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+
+// OperationType is the type of a PATCH operation.
+type OperationType string
+
+const (
+	Add     OperationType = "add"
+	Remove  OperationType = "remove"
+	Replace OperationType = "replace"
+)
+
+// Operation is a single PATCH operation.
+type Operation struct {
+	Op    OperationType `json:"op"`
+	Path  string        `json:"path,omitempty"`
+	Value interface{}   `json:"value,omitempty"`
+}
+
+// Request is the body of a PATCH request.
+type Request struct {
+	Schemas    []string    `json:"schemas"`
+	Operations []Operation `json:"Operations"`
+}
+
+// Apply applies the operations, in order, to a copy of the given resource.
+// The reference schema (and its extensions) decide whether attributes are multi valued or complex,
+// and whether they can be modified. Extension attributes are stored in a complex attribute named
+// after the URN of the extension, which is also added to (or removed from) the "schemas" attribute if present.
+//
+// Either all operations are applied, or an error is returned. Errors are of type *validate.Error.
+// Operations that remove values that do not match the value filter are ignored.
+func Apply(resource map[string]interface{}, operations []Operation, s schema.ReferenceSchema, ext ...schema.ReferenceSchema) (map[string]interface{}, error) {
+	p := patcher{
+		s:        s,
+		ext:      ext,
+		resource: normalize(resource).(map[string]interface{}),
+	}
+	for _, op := range operations {
+		if err := p.apply(op); err != nil {
+			return nil, err
+		}
+	}
+	return p.resource, nil
+}
+
+type patcher struct {
+	s        schema.ReferenceSchema
+	ext      []schema.ReferenceSchema
+	resource map[string]interface{}
+}
+
+func (p *patcher) apply(op Operation) error {
+	typ := OperationType(strings.ToLower(string(op.Op)))
+	switch typ {
+	case Add, Replace:
+	case Remove:
+		if op.Path == "" {
+			return errorf(validate.NoTarget, "", "remove operations require a path")
+		}
+	default:
+		return errorf(validate.InvalidSyntax, op.Path, "unknown operation %q", op.Op)
+	}
+	value := normalize(op.Value)
+
+	if op.Path == "" {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return errorf(validate.InvalidValue, "", "operations without path require a complex value")
+		}
+		return p.applyAll(typ, m, nil)
+	}
+
+	if e, ok := p.extension(op.Path); ok {
+		if typ == Remove {
+			return p.removeExtension(e)
+		}
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return errorf(validate.InvalidValue, op.Path, "extension value must be complex")
+		}
+		return p.applyAll(typ, m, &e)
+	}
+
+	path, err := filter.ParsePath(op.Path)
+	if err != nil {
+		return errorf(validate.InvalidPath, op.Path, "%s", err)
+	}
+	t, err := p.resolve(path, nil)
+	if err != nil {
+		return err
+	}
+	return p.applyTarget(typ, t, value)
+}
+
+// applyAll applies the operation to every attribute within the value, within the extension if not nil.
+func (p *patcher) applyAll(typ OperationType, value map[string]interface{}, e *schema.ReferenceSchema) error {
+	keys := make([]string, 0, len(value))
+	for k := range value {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if e == nil {
+			if ext, ok := p.extension(k); ok {
+				m, ok := value[k].(map[string]interface{})
+				if !ok {
+					return errorf(validate.InvalidValue, k, "extension value must be complex")
+				}
+				if err := p.applyAll(typ, m, &ext); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		path, err := filter.ParsePath(k)
+		if err != nil || path.ValueFilter != nil {
+			return errorf(validate.InvalidPath, k, "invalid attribute name")
+		}
+		t, err := p.resolve(path, e)
+		if err != nil {
+			return err
+		}
+		if err := p.applyTarget(typ, t, value[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *patcher) applyTarget(typ OperationType, t target, value interface{}) error {
+	var err error
+	switch typ {
+	case Add:
+		err = p.add(t, value)
+	case Replace:
+		err = p.replace(t, value)
+	case Remove:
+		err = p.remove(t, value)
+	}
+	if err != nil {
+		return err
+	}
+	return p.finish(t)
+}
+
+func (p *patcher) add(t target, value interface{}) error {
+	if value == nil {
+		return errorf(validate.InvalidValue, t.String(), "add operations require a value")
+	}
+	container := p.container(t, true)
+	name := attributes.ExistingKey(t.attribute.Name, container)
+	current, found := container[name]
+	if current == nil {
+		found = false
+	}
+
+	switch {
+	case t.filter == nil && t.sub == nil:
+		if !found {
+			if err := t.mutable(false); err != nil {
+				return err
+			}
+			if t.attribute.MultiValued {
+				values := attributes.ToSlice(value)
+				container[name] = values
+				return primary(t.attribute, values, indexes(0, len(values)))
+			}
+			container[name] = value
+			return nil
+		}
+		if t.attribute.MultiValued {
+			// New values are new records, so immutable attributes can be set.
+			if err := t.mutable(false); err != nil {
+				return err
+			}
+			values := attributes.ToSlice(current)
+			var added []int
+			for _, v := range attributes.ToSlice(value) {
+				if i := indexOf(values, v); i != -1 {
+					added = append(added, i)
+					continue
+				}
+				values = append(values, v)
+				added = append(added, len(values)-1)
+			}
+			container[name] = values
+			return primary(t.attribute, values, added)
+		}
+		if t.attribute.Type == schema.ComplexType {
+			return p.merge(t, current, value)
+		}
+		if err := t.mutable(!reflect.DeepEqual(current, value)); err != nil {
+			return err
+		}
+		container[name] = value
+		return nil
+	case t.filter == nil:
+		return p.setSub(t, container, name, current, value, true)
+	default:
+		return p.setFiltered(t, current, value, true)
+	}
+}
+
+func (p *patcher) replace(t target, value interface{}) error {
+	container := p.container(t, true)
+	name := attributes.ExistingKey(t.attribute.Name, container)
+	current, found := container[name]
+	if !found || current == nil {
+		if t.filter != nil {
+			return errorf(validate.NoTarget, t.String(), "no values match the filter")
+		}
+		if value == nil {
+			return nil
+		}
+		return p.add(t, value)
+	}
+
+	switch {
+	case t.filter == nil && t.sub == nil:
+		if t.attribute.Type == schema.ComplexType && !t.attribute.MultiValued {
+			return p.merge(t, current, value)
+		}
+		if err := t.mutable(!reflect.DeepEqual(current, value)); err != nil {
+			return err
+		}
+		if value == nil {
+			return p.remove(t, nil)
+		}
+		if t.attribute.MultiValued {
+			values := attributes.ToSlice(value)
+			container[name] = values
+			return primary(t.attribute, values, indexes(0, len(values)))
+		}
+		container[name] = value
+		return nil
+	case t.filter == nil:
+		return p.setSub(t, container, name, current, value, false)
+	default:
+		return p.setFiltered(t, current, value, false)
+	}
+}
+
+func (p *patcher) remove(t target, value interface{}) error {
+	container := p.container(t, false)
+	if container == nil {
+		return nil
+	}
+	name := attributes.ExistingKey(t.attribute.Name, container)
+	current, found := container[name]
+	if !found || current == nil {
+		return nil
+	}
+
+	switch {
+	case t.filter == nil && t.sub == nil:
+		if t.attribute.MultiValued && value != nil {
+			// Not defined by the RFC, but commonly used to remove specific values (e.g. group members).
+			if err := t.mutable(true); err != nil {
+				return err
+			}
+			var remaining []interface{}
+			for _, v := range attributes.ToSlice(current) {
+				removed := false
+				for _, r := range attributes.ToSlice(value) {
+					removed = removed || equal(v, r)
+				}
+				if !removed {
+					remaining = append(remaining, v)
+				}
+			}
+			container[name] = remaining
+			return nil
+		}
+		if err := t.mutable(true); err != nil {
+			return err
+		}
+		if t.attribute.Required {
+			return errorf(validate.InvalidValue, t.String(), "required attribute can not be removed")
+		}
+		delete(container, name)
+		return nil
+	case t.filter == nil:
+		if err := t.mutable(true); err != nil {
+			return err
+		}
+		if t.sub.Required {
+			return errorf(validate.InvalidValue, t.String(), "required attribute can not be removed")
+		}
+		for _, e := range elements(current) {
+			delete(e, attributes.ExistingKey(t.sub.Name, e))
+		}
+		return nil
+	default:
+		matches, err := t.match(current)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return nil
+		}
+		if err := t.mutable(true); err != nil {
+			return err
+		}
+		values := attributes.ToSlice(current)
+		if t.sub != nil {
+			if t.sub.Required {
+				return errorf(validate.InvalidValue, t.String(), "required attribute can not be removed")
+			}
+			for _, i := range matches {
+				if e, ok := values[i].(map[string]interface{}); ok {
+					delete(e, attributes.ExistingKey(t.sub.Name, e))
+				}
+			}
+			return nil
+		}
+		var remaining []interface{}
+		for i, v := range values {
+			if len(matches) != 0 && matches[0] == i {
+				matches = matches[1:]
+				continue
+			}
+			remaining = append(remaining, v)
+		}
+		container[name] = remaining
+		return nil
+	}
+}
+
+// merge sets all sub attributes within the value on the current (single valued) complex attribute.
+func (p *patcher) merge(t target, current, value interface{}) error {
+	c, ok := current.(map[string]interface{})
+	if !ok {
+		return errorf(validate.InvalidValue, t.String(), "attribute is not complex")
+	}
+	v, ok := value.(map[string]interface{})
+	if !ok {
+		return errorf(validate.InvalidValue, t.String(), "value must be complex")
+	}
+	for k, sv := range v {
+		sub := attributes.FindAttribute(k, t.attribute.SubAttributes)
+		if sub == nil {
+			return errorf(validate.InvalidPath, t.String()+"."+k, "unknown attribute")
+		}
+		st := t.withSub(sub)
+		key := attributes.ExistingKey(sub.Name, c)
+		if err := st.mutable(c[key] != nil && !reflect.DeepEqual(c[key], sv)); err != nil {
+			return err
+		}
+		c[key] = sv
+	}
+	return nil
+}
+
+// setSub sets the sub attribute of a complex attribute, for multi valued attributes the sub attribute of every value is set.
+func (p *patcher) setSub(t target, container map[string]interface{}, name string, current, value interface{}, add bool) error {
+	if !t.attribute.MultiValued {
+		if current == nil {
+			current = make(map[string]interface{})
+			container[name] = current
+		}
+		c, ok := current.(map[string]interface{})
+		if !ok {
+			return errorf(validate.InvalidValue, t.String(), "attribute is not complex")
+		}
+		return setSubValue(t, c, value, add)
+	}
+
+	values := elements(current)
+	if len(values) == 0 {
+		return errorf(validate.NoTarget, t.String(), "attribute has no values")
+	}
+	for _, e := range values {
+		if err := setSubValue(t, e, value, add); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFiltered modifies the values of a multi valued attribute that match the filter.
+func (p *patcher) setFiltered(t target, current, value interface{}, add bool) error {
+	matches, err := t.match(current)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return errorf(validate.NoTarget, t.String(), "no values match the filter")
+	}
+
+	values := attributes.ToSlice(current)
+	for _, i := range matches {
+		e, ok := values[i].(map[string]interface{})
+		if !ok {
+			return errorf(validate.InvalidValue, t.String(), "value is not complex")
+		}
+		if t.sub != nil {
+			if err := setSubValue(t, e, value, add); err != nil {
+				return err
+			}
+			continue
+		}
+
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			return errorf(validate.InvalidValue, t.String(), "value must be complex")
+		}
+		if !add {
+			// Replace the whole value, read only sub attributes are kept.
+			for k := range e {
+				if _, ok := v[k]; !ok {
+					sub := attributes.FindAttribute(k, t.attribute.SubAttributes)
+					if sub != nil && sub.Mutability == schema.ReadOnly {
+						continue
+					}
+					if sub != nil {
+						if err := t.withSub(sub).mutable(true); err != nil {
+							return err
+						}
+					}
+					delete(e, k)
+				}
+			}
+		}
+		for k, sv := range v {
+			sub := attributes.FindAttribute(k, t.attribute.SubAttributes)
+			if sub == nil {
+				return errorf(validate.InvalidPath, t.String()+"."+k, "unknown attribute")
+			}
+			if err := setSubValue(t.withSub(sub), e, sv, add); err != nil {
+				return err
+			}
+		}
+	}
+	return primary(t.attribute, values, matches)
+}
+
+func setSubValue(t target, element map[string]interface{}, value interface{}, add bool) error {
+	key := attributes.ExistingKey(t.sub.Name, element)
+	current := element[key]
+	if current == nil {
+		if err := t.mutable(false); err != nil {
+			return err
+		}
+		if t.sub.MultiValued {
+			value = attributes.ToSlice(value)
+		}
+		element[key] = value
+		return nil
+	}
+
+	if t.sub.MultiValued && add {
+		values := attributes.ToSlice(current)
+		for _, v := range attributes.ToSlice(value) {
+			if indexOf(values, v) == -1 {
+				values = append(values, v)
+			}
+		}
+		value = values
+	} else if t.sub.MultiValued {
+		value = attributes.ToSlice(value)
+	}
+	if err := t.mutable(!reflect.DeepEqual(current, value)); err != nil {
+		return err
+	}
+	if value == nil {
+		delete(element, key)
+		return nil
+	}
+	element[key] = value
+	return nil
+}
+
+// finish validates the new value of the attribute and removes empty attributes and extensions.
+func (p *patcher) finish(t target) error {
+	container := p.container(t, false)
+	if container == nil {
+		return nil
+	}
+	name := attributes.ExistingKey(t.attribute.Name, container)
+	if value, ok := container[name]; ok && empty(value) {
+		delete(container, name)
+	}
+	if value, ok := container[name]; ok {
+		if err := validate.Attribute(name, value, t.attribute); err != nil {
+			errs := err.(validate.Errors)
+			if t.ext != nil {
+				errs[0].Path = t.ext.ID + ":" + errs[0].Path
+			}
+			return errs[0]
+		}
+	}
+	if t.ext != nil && len(container) == 0 {
+		return p.removeExtension(*t.ext)
+	}
+	return nil
+}
+
+// container returns the map that holds the attribute, nil if the extension is not present.
+// If create is set, missing extensions are created.
+func (p *patcher) container(t target, create bool) map[string]interface{} {
+	if t.ext == nil {
+		return p.resource
+	}
+	key := attributes.ExistingKey(t.ext.ID, p.resource)
+	if m, ok := p.resource[key].(map[string]interface{}); ok {
+		return m
+	}
+	if !create {
+		return nil
+	}
+	m := make(map[string]interface{})
+	p.resource[key] = m
+
+	schemasKey := attributes.ExistingKey(schema.SchemasAttribute.Name, p.resource)
+	if schemas, ok := p.resource[schemasKey]; ok && !containsValue(attributes.ToSlice(schemas), t.ext.ID) {
+		p.resource[schemasKey] = append(attributes.ToSlice(schemas), t.ext.ID)
+	}
+	return m
+}
+
+func (p *patcher) removeExtension(e schema.ReferenceSchema) error {
+	key := attributes.ExistingKey(e.ID, p.resource)
+	if m, ok := p.resource[key].(map[string]interface{}); ok {
+		for k := range m {
+			t := target{attribute: attributes.FindAttribute(k, e.Attributes), ext: &e}
+			if t.attribute == nil {
+				continue
+			}
+			t.path = filter.Path{AttributePath: filter.AttributePath{URI: e.ID, AttributeName: t.attribute.Name}}
+			if err := t.mutable(true); err != nil {
+				return err
+			}
+			if t.attribute.Required {
+				return errorf(validate.InvalidValue, t.String(), "required attribute can not be removed")
+			}
+		}
+	}
+	delete(p.resource, key)
+
+	schemasKey := attributes.ExistingKey(schema.SchemasAttribute.Name, p.resource)
+	if schemas, ok := p.resource[schemasKey]; ok {
+		var remaining []interface{}
+		for _, s := range attributes.ToSlice(schemas) {
+			if str, ok := s.(string); !ok || !strings.EqualFold(str, e.ID) {
+				remaining = append(remaining, s)
+			}
+		}
+		p.resource[schemasKey] = remaining
+	}
+	return nil
+}
+
+// extension returns the extension with the given URN.
+func (p *patcher) extension(urn string) (schema.ReferenceSchema, bool) {
+	for _, e := range p.ext {
+		if strings.EqualFold(urn, e.ID) {
+			return e, true
+		}
+	}
+	return schema.ReferenceSchema{}, false
+}
+
+func errorf(typ validate.ScimType, path, format string, args ...interface{}) *validate.Error {
+	return &validate.Error{
+		ScimType: typ,
+		Path:     path,
+		Detail:   fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,305 @@
+package patch_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/memsql/scimtools/patch"
+	"github.com/memsql/scimtools/schema"
+	"github.com/memsql/scimtools/validate"
+)
+
+var (
+	userSchema = schema.ReferenceSchema{
+		ID:   "urn:ietf:params:scim:schemas:core:2.0:User",
+		Name: "User",
+		Attributes: []*schema.Attribute{
+			{Name: "userName", Type: schema.StringType, Required: true},
+			{Name: "nickName", Type: schema.StringType},
+			{Name: "active", Type: schema.BooleanType},
+			{Name: "employeeId", Type: schema.StringType, Mutability: schema.Immutable},
+			{
+				Name: "name",
+				Type: schema.ComplexType,
+				SubAttributes: []*schema.Attribute{
+					{Name: "givenName", Type: schema.StringType},
+					{Name: "familyName", Type: schema.StringType},
+				},
+			},
+			{
+				Name:        "emails",
+				Type:        schema.ComplexType,
+				MultiValued: true,
+				SubAttributes: []*schema.Attribute{
+					{Name: "value", Type: schema.StringType},
+					{Name: "type", Type: schema.StringType},
+					{Name: "primary", Type: schema.BooleanType},
+				},
+			},
+			{
+				Name:        "groups",
+				Type:        schema.ComplexType,
+				MultiValued: true,
+				Mutability:  schema.ReadOnly,
+				SubAttributes: []*schema.Attribute{
+					{Name: "value", Type: schema.StringType},
+				},
+			},
+		},
+	}
+	groupSchema = schema.ReferenceSchema{
+		ID:   "urn:ietf:params:scim:schemas:core:2.0:Group",
+		Name: "Group",
+		Attributes: []*schema.Attribute{
+			{Name: "displayName", Type: schema.StringType, Required: true},
+			{
+				Name:        "members",
+				Type:        schema.ComplexType,
+				MultiValued: true,
+				SubAttributes: []*schema.Attribute{
+					{Name: "value", Type: schema.StringType, Mutability: schema.Immutable},
+					{Name: "display", Type: schema.StringType},
+				},
+			},
+		},
+	}
+	enterpriseSchema = schema.ReferenceSchema{
+		ID:   "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+		Name: "EnterpriseUser",
+		Attributes: []*schema.Attribute{
+			{Name: "employeeNumber", Type: schema.StringType},
+		},
+	}
+)
+
+func newUser() map[string]interface{} {
+	return map[string]interface{}{
+		"schemas":    []interface{}{userSchema.ID},
+		"id":         "2819c223",
+		"userName":   "bjensen",
+		"employeeId": "701984",
+		"name":       map[string]interface{}{"givenName": "Barbara"},
+		"emails": []interface{}{
+			map[string]interface{}{"value": "bjensen@example.com", "type": "work", "primary": true},
+			map[string]interface{}{"value": "babs@jensen.org", "type": "home"},
+		},
+	}
+}
+
+func ExampleApply() {
+	group := map[string]interface{}{
+		"displayName": "Tour Guides",
+		"members": []interface{}{
+			map[string]interface{}{"value": "2819c223", "display": "Babs Jensen"},
+			map[string]interface{}{"value": "902c246b", "display": "Mandy Pepperidge"},
+		},
+	}
+	group, err := patch.Apply(group, []patch.Operation{
+		{Op: patch.Remove, Path: `members[value eq "2819c223"]`},
+		{Op: patch.Add, Path: "members", Value: []interface{}{
+			map[string]interface{}{"value": "e9e30dba", "display": "Jim Smith"},
+		}},
+		{Op: patch.Replace, Path: "displayName", Value: "Guides"},
+	}, groupSchema)
+	fmt.Println(group, err)
+
+	_, err = patch.Apply(group, []patch.Operation{
+		{Op: patch.Replace, Path: `members[display eq "Jim Smith"].value`, Value: "6f3b4b2a"},
+	}, groupSchema)
+	fmt.Println(err)
+
+	var e *validate.Error
+	if errors.As(err, &e) {
+		fmt.Println(e.ScimType)
+	}
+
+	// Output:
+	// map[displayName:Guides members:[map[display:Mandy Pepperidge value:902c246b] map[display:Jim Smith value:e9e30dba]]] <nil>
+	// members[display eq "Jim Smith"].value: attribute is immutable
+	// mutability
+}
+
+func TestApply(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		operations []patch.Operation
+		expected   map[string]interface{}
+	}{
+		{
+			name: "add",
+			operations: []patch.Operation{
+				{Op: "Add", Path: "nickName", Value: "Babs"},
+				{Op: "add", Path: "name.familyName", Value: "Jensen"},
+				{Op: "add", Path: "emails", Value: []interface{}{
+					map[string]interface{}{"value": "babs@example.com", "primary": true},
+					map[string]interface{}{"value": "babs@jensen.org"},
+				}},
+			},
+			expected: map[string]interface{}{
+				"nickName": "Babs",
+				"name":     map[string]interface{}{"givenName": "Barbara", "familyName": "Jensen"},
+				"emails": []interface{}{
+					map[string]interface{}{"value": "bjensen@example.com", "type": "work", "primary": false},
+					map[string]interface{}{"value": "babs@jensen.org", "type": "home"},
+					map[string]interface{}{"value": "babs@example.com", "primary": true},
+				},
+			},
+		},
+		{
+			name: "add without path",
+			operations: []patch.Operation{
+				{Op: "add", Value: map[string]interface{}{
+					"nickName":          "Babs",
+					"name":              map[string]interface{}{"familyName": "Jensen"},
+					enterpriseSchema.ID: map[string]interface{}{"employeeNumber": "701984"},
+				}},
+			},
+			expected: map[string]interface{}{
+				"schemas":           []interface{}{userSchema.ID, enterpriseSchema.ID},
+				"nickName":          "Babs",
+				"name":              map[string]interface{}{"givenName": "Barbara", "familyName": "Jensen"},
+				enterpriseSchema.ID: map[string]interface{}{"employeeNumber": "701984"},
+			},
+		},
+		{
+			name: "replace",
+			operations: []patch.Operation{
+				{Op: "replace", Path: "USERNAME", Value: "babs"},
+				{Op: "replace", Path: "active", Value: false},
+				{Op: "replace", Path: `emails[type eq "home"]`, Value: map[string]interface{}{"value": "babs@example.com", "primary": true}},
+				{Op: "replace", Path: `emails[type eq "work"].type`, Value: "other"},
+			},
+			expected: map[string]interface{}{
+				"userName": "babs",
+				"active":   false,
+				"emails": []interface{}{
+					map[string]interface{}{"value": "bjensen@example.com", "type": "other", "primary": false},
+					map[string]interface{}{"value": "babs@example.com", "primary": true},
+				},
+			},
+		},
+		{
+			name: "replace without path",
+			operations: []patch.Operation{
+				{Op: "replace", Value: map[string]interface{}{
+					"userName": "babs",
+					"name":     map[string]interface{}{"familyName": "Jensen"},
+					"emails":   []interface{}{map[string]interface{}{"value": "babs@example.com"}},
+				}},
+			},
+			expected: map[string]interface{}{
+				"userName": "babs",
+				"name":     map[string]interface{}{"givenName": "Barbara", "familyName": "Jensen"},
+				"emails":   []interface{}{map[string]interface{}{"value": "babs@example.com"}},
+			},
+		},
+		{
+			name: "remove",
+			operations: []patch.Operation{
+				{Op: "remove", Path: "name.givenName"},
+				{Op: "remove", Path: `emails[type eq "work"]`},
+				{Op: "remove", Path: `emails[type eq "other"]`},
+				{Op: "remove", Path: "nickName"},
+			},
+			expected: map[string]interface{}{
+				"name": nil,
+				"emails": []interface{}{
+					map[string]interface{}{"value": "babs@jensen.org", "type": "home"},
+				},
+			},
+		},
+		{
+			name: "remove values",
+			operations: []patch.Operation{
+				{Op: "remove", Path: "emails", Value: []interface{}{
+					map[string]interface{}{"value": "bjensen@example.com"},
+					map[string]interface{}{"value": "babs@jensen.org"},
+				}},
+			},
+			expected: map[string]interface{}{"emails": nil},
+		},
+		{
+			name: "extension",
+			operations: []patch.Operation{
+				{Op: "add", Path: enterpriseSchema.ID + ":employeeNumber", Value: "701984"},
+				{Op: "remove", Path: enterpriseSchema.ID + ":employeeNumber"},
+			},
+			expected: map[string]interface{}{},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			user := newUser()
+			result, err := patch.Apply(user, test.operations, userSchema, enterpriseSchema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(user, newUser()) {
+				t.Error("expected the original resource to be unchanged")
+			}
+
+			// The expected attributes are applied to the original resource, nil values are removed.
+			expected := newUser()
+			for k, v := range test.expected {
+				if v == nil {
+					delete(expected, k)
+					continue
+				}
+				expected[k] = v
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("expected %v, got %v", expected, result)
+			}
+		})
+	}
+}
+
+func TestApply_invalid(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		operation patch.Operation
+		scimType  validate.ScimType
+	}{
+		{"unknown operation", patch.Operation{Op: "move", Path: "nickName"}, validate.InvalidSyntax},
+		{"remove without path", patch.Operation{Op: "remove"}, validate.NoTarget},
+		{"unknown attribute", patch.Operation{Op: "add", Path: "title", Value: "Tour Guide"}, validate.InvalidPath},
+		{"invalid path", patch.Operation{Op: "add", Path: `emails[type eq`, Value: "x"}, validate.InvalidPath},
+		{"invalid value", patch.Operation{Op: "replace", Path: "active", Value: "yes"}, validate.InvalidValue},
+		{"no match", patch.Operation{Op: "replace", Path: `emails[type eq "other"].value`, Value: "x"}, validate.NoTarget},
+		{"read only", patch.Operation{Op: "replace", Path: "id", Value: "x"}, validate.Mutability},
+		{"read only values", patch.Operation{Op: "add", Path: "groups", Value: map[string]interface{}{"value": "x"}}, validate.Mutability},
+		{"immutable", patch.Operation{Op: "replace", Path: "employeeId", Value: "x"}, validate.Mutability},
+		{"required", patch.Operation{Op: "remove", Path: "userName"}, validate.InvalidValue},
+		{"primary", patch.Operation{Op: "add", Path: "emails", Value: []interface{}{
+			map[string]interface{}{"value": "a@example.com", "primary": true},
+			map[string]interface{}{"value": "b@example.com", "primary": true},
+		}}, validate.InvalidValue},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := patch.Apply(newUser(), []patch.Operation{test.operation}, userSchema, enterpriseSchema)
+			var e *validate.Error
+			if !errors.As(err, &e) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+			if e.ScimType != test.scimType {
+				t.Errorf("expected %s, got %s (%s)", test.scimType, e.ScimType, e)
+			}
+		})
+	}
+}
+
+func TestApply_immutable(t *testing.T) {
+	user := newUser()
+	delete(user, "employeeId")
+	user, err := patch.Apply(user, []patch.Operation{
+		{Op: "add", Path: "employeeId", Value: "701984"},
+		{Op: "replace", Path: "employeeId", Value: "701984"},
+	}, userSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user["employeeId"] != "701984" {
+		t.Errorf("expected employeeId to be set, got %v", user["employeeId"])
+	}
+}
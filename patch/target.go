@@ -0,0 +1,236 @@
+package patch
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/memsql/scimtools/attributes"
+	"github.com/memsql/scimtools/filter"
+	"github.com/memsql/scimtools/schema"
+	"github.com/memsql/scimtools/validate"
+)
+
+// target is the attribute (or sub attribute) an operation refers to.
+type target struct {
+	path      filter.Path
+	attribute *schema.Attribute
+	sub       *schema.Attribute
+	filter    filter.Expression
+	// ext is the extension that defines the attribute, nil for the core schema.
+	ext *schema.ReferenceSchema
+}
+
+// resolve looks up the attribute definitions the path refers to, within the extension if not nil.
+func (p *patcher) resolve(path filter.Path, ext *schema.ReferenceSchema) (target, error) {
+	attrs := p.s.Attributes
+	switch uri := path.AttributePath.URI; {
+	case ext != nil:
+		path.AttributePath.URI = ext.ID
+		attrs = ext.Attributes
+	case uri == "" || strings.EqualFold(uri, p.s.ID):
+	default:
+		e, ok := p.extension(uri)
+		if !ok {
+			return target{}, errorf(validate.InvalidPath, path.String(), "unknown schema %q", uri)
+		}
+		ext = &e
+		attrs = e.Attributes
+	}
+
+	t := target{
+		path:   path,
+		filter: path.ValueFilter,
+		ext:    ext,
+	}
+	t.attribute = attributes.FindAttribute(path.AttributePath.AttributeName, attrs)
+	if t.attribute == nil && ext == nil {
+		t.attribute = attributes.FindAttribute(path.AttributePath.AttributeName, schema.CoreAttributes)
+	}
+	if t.attribute == nil {
+		return target{}, errorf(validate.InvalidPath, path.String(), "unknown attribute")
+	}
+	if t.filter != nil && (!t.attribute.MultiValued || t.attribute.Type != schema.ComplexType) {
+		return target{}, errorf(validate.InvalidFilter, path.String(), "value filters require a multi valued complex attribute")
+	}
+	if name := path.AttributePath.SubAttribute; name != "" {
+		if t.attribute.Type != schema.ComplexType {
+			return target{}, errorf(validate.InvalidPath, path.String(), "attribute is not complex")
+		}
+		if t.sub = attributes.FindAttribute(name, t.attribute.SubAttributes); t.sub == nil {
+			return target{}, errorf(validate.InvalidPath, path.String(), "unknown attribute")
+		}
+	}
+	return t, nil
+}
+
+func (t target) String() string {
+	path := t.path
+	if t.sub != nil {
+		path.AttributePath.SubAttribute = t.sub.Name
+	}
+	return path.String()
+}
+
+// withSub returns the target of the given sub attribute.
+func (t target) withSub(sub *schema.Attribute) target {
+	t.sub = sub
+	return t
+}
+
+// mutable checks whether the target can be set, modify indicates that an existing value gets changed.
+func (t target) mutable(modify bool) error {
+	for _, attribute := range []*schema.Attribute{t.attribute, t.sub} {
+		if attribute == nil {
+			continue
+		}
+		switch attribute.Mutability {
+		case schema.ReadOnly:
+			return errorf(validate.Mutability, t.String(), "attribute is read only")
+		case schema.Immutable:
+			if modify {
+				return errorf(validate.Mutability, t.String(), "attribute is immutable")
+			}
+		}
+	}
+	return nil
+}
+
+// match returns the indexes of the values that match the value filter.
+func (t target) match(current interface{}) ([]int, error) {
+	var indexes []int
+	for i, v := range attributes.ToSlice(current) {
+		e, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ok, err := attributes.MatchElement(e, t.filter, t.attribute)
+		if err != nil {
+			return nil, errorf(validate.InvalidFilter, t.String(), "%s", err)
+		}
+		if ok {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
+
+// primary makes sure only one value of a multi valued complex attribute is primary.
+// If one of the changed values is primary, the primary sub attribute of all the others is set to false.
+func primary(attribute *schema.Attribute, values []interface{}, changed []int) error {
+	if attribute.Type != schema.ComplexType || attributes.FindAttribute("primary", attribute.SubAttributes) == nil {
+		return nil
+	}
+	index := -1
+	for _, i := range changed {
+		if e, ok := values[i].(map[string]interface{}); ok && e[attributes.ExistingKey("primary", e)] == true {
+			if index != -1 {
+				return errorf(validate.InvalidValue, attribute.Name, "only one value can be primary")
+			}
+			index = i
+		}
+	}
+	if index == -1 {
+		return nil
+	}
+	for i, v := range values {
+		if e, ok := v.(map[string]interface{}); ok && i != index && e[attributes.ExistingKey("primary", e)] == true {
+			e[attributes.ExistingKey("primary", e)] = false
+		}
+	}
+	return nil
+}
+
+// normalize returns a deep copy of the value where all maps are converted to map[string]interface{} and
+// all slices to []interface{}.
+func normalize(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return value
+		}
+		m := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			m[k.String()] = normalize(v.MapIndex(k).Interface())
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		s := make([]interface{}, v.Len())
+		for i := range s {
+			s[i] = normalize(v.Index(i).Interface())
+		}
+		return s
+	default:
+		return value
+	}
+}
+
+// elements returns the complex values of a (multi valued) complex attribute.
+func elements(value interface{}) []map[string]interface{} {
+	var elements []map[string]interface{}
+	for _, v := range attributes.ToSlice(value) {
+		if e, ok := v.(map[string]interface{}); ok {
+			elements = append(elements, e)
+		}
+	}
+	return elements
+}
+
+func indexes(from, to int) []int {
+	indexes := make([]int, 0, to-from)
+	for i := from; i < to; i++ {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// indexOf returns the index of the first value that equals the given value, -1 if not found.
+// Complex values are equal if all the sub attributes of the given value are equal.
+func indexOf(values []interface{}, value interface{}) int {
+	for i, v := range values {
+		if equal(v, value) {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	return indexOf(values, value) != -1
+}
+
+func equal(v, value interface{}) bool {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return reflect.DeepEqual(v, value)
+	}
+	e, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for k, sv := range m {
+		if !reflect.DeepEqual(e[attributes.ExistingKey(k, e)], sv) {
+			return false
+		}
+	}
+	return true
+}
+
+func empty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,173 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is the kind of change of an attribute.
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change is a difference between two versions of a schema.
+type Change struct {
+	// Path is the (dotted) name of the attribute, empty if the change concerns the schema itself.
+	Path string     `json:"path"`
+	Kind ChangeKind `json:"kind"`
+	// Characteristic is the name of the characteristic that changed (e.g. "type"), only set if the kind is Changed.
+	Characteristic string      `json:"characteristic,omitempty"`
+	Old            interface{} `json:"old,omitempty"`
+	New            interface{} `json:"new,omitempty"`
+	// Breaking indicates whether the change can break existing clients.
+	Breaking bool `json:"breaking"`
+}
+
+func (c Change) String() string {
+	compatibility := "compatible"
+	if c.Breaking {
+		compatibility = "breaking"
+	}
+	path := c.Path
+	if path == "" {
+		path = "schema"
+	}
+	if c.Kind != Changed {
+		return fmt.Sprintf("%s: %s %s", compatibility, path, c.Kind)
+	}
+	return fmt.Sprintf("%s: %s %s changed from %v to %v", compatibility, path, c.Characteristic, c.Old, c.New)
+}
+
+// Changes is a list of changes, ordered by attribute path.
+type Changes []Change
+
+// Breaking returns whether any of the changes can break existing clients.
+func (c Changes) Breaking() bool {
+	for _, change := range c {
+		if change.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare reports the differences between two versions of a schema, attributes are matched by name (case insensitive).
+// Empty characteristics are compared as their default value.
+//
+// Changes are breaking if a client of the old version can not use the new version, e.g. removed attributes,
+// new required attributes, changed types or stricter characteristics (mutability, returned, uniqueness, ...).
+func Compare(old, new ReferenceSchema) Changes {
+	var changes Changes
+	if !strings.EqualFold(old.ID, new.ID) {
+		changes = append(changes, Change{Kind: Changed, Characteristic: "id", Old: old.ID, New: new.ID, Breaking: true})
+	}
+	if old.Description != new.Description {
+		changes = append(changes, Change{Kind: Changed, Characteristic: "description", Old: old.Description, New: new.Description})
+	}
+	changes = append(changes, compareAttributes("", old.Attributes, new.Attributes)...)
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func compareAttributes(prefix string, old, new []*Attribute) Changes {
+	var changes Changes
+	for _, o := range old {
+		n := findPath(o.Name, new)
+		if n == nil {
+			changes = append(changes, Change{Path: prefix + o.Name, Kind: Removed, Breaking: true})
+			continue
+		}
+		changes = append(changes, compareAttribute(prefix+n.Name, o, n)...)
+	}
+	for _, n := range new {
+		if findPath(n.Name, old) != nil {
+			continue
+		}
+		d := defaults(n)
+		changes = append(changes, Change{
+			Path:     prefix + n.Name,
+			Kind:     Added,
+			Breaking: d.Required && d.Mutability != ReadOnly,
+		})
+	}
+	return changes
+}
+
+// rank orders the values of the characteristics from least to most strict.
+var rank = map[interface{}]int{
+	ReadWrite: 0, Immutable: 1, WriteOnly: 1, ReadOnly: 2,
+	Always: 0, Default: 1, Request: 2, Never: 3,
+	None: 0, Server: 1, Global: 2,
+}
+
+func compareAttribute(path string, old, new *Attribute) Changes {
+	o, n := defaults(old), defaults(new)
+	var changes Changes
+	change := func(characteristic string, old, new interface{}, breaking bool) {
+		if reflect.DeepEqual(old, new) {
+			return
+		}
+		changes = append(changes, Change{
+			Path:           path,
+			Kind:           Changed,
+			Characteristic: characteristic,
+			Old:            old,
+			New:            new,
+			Breaking:       breaking,
+		})
+	}
+
+	change("type", o.Type, n.Type, true)
+	change("multiValued", o.MultiValued, n.MultiValued, true)
+	change("description", o.Description, n.Description, false)
+	change("required", o.Required, n.Required, n.Required)
+	change("caseExact", o.CaseExact, n.CaseExact, n.CaseExact)
+	change("mutability", o.Mutability, n.Mutability, rank[n.Mutability] > rank[o.Mutability] || n.Mutability == WriteOnly)
+	change("returned", o.Returned, n.Returned, rank[n.Returned] > rank[o.Returned])
+	change("uniqueness", o.Uniqueness, n.Uniqueness, rank[n.Uniqueness] > rank[o.Uniqueness])
+	change("canonicalValues", o.CanonicalValues, n.CanonicalValues, restricted(o.CanonicalValues, n.CanonicalValues))
+	change("referenceTypes", o.ReferenceTypes, n.ReferenceTypes, restricted(o.ReferenceTypes, n.ReferenceTypes))
+
+	if o.Type == ComplexType && n.Type == ComplexType {
+		changes = append(changes, compareAttributes(path+".", o.SubAttributes, n.SubAttributes)...)
+	}
+	return changes
+}
+
+// restricted returns whether the new list of values no longer allows some of the old values.
+// An empty list allows all values.
+func restricted(old, new []string) bool {
+	if len(new) == 0 {
+		return false
+	}
+	if len(old) == 0 {
+		return true
+	}
+	for _, o := range old {
+		if !containsFold(new, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaults returns a copy of the attribute with the empty characteristics set to their default value.
+func defaults(attribute *Attribute) Attribute {
+	a := *attribute
+	a.normalize()
+	if len(a.CanonicalValues) == 0 {
+		a.CanonicalValues = nil
+	}
+	if len(a.ReferenceTypes) == 0 {
+		a.ReferenceTypes = nil
+	}
+	return a
+}
@@ -0,0 +1,131 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/memsql/scimtools/schema"
+)
+
+func ExampleCompare() {
+	old := schema.ReferenceSchema{
+		ID: "urn:example:params:scim:schemas:extension:employee:2.0:User",
+		Attributes: []*schema.Attribute{
+			{Name: "employeeNumber", Type: schema.StringType},
+			{Name: "badges", Type: schema.StringType, MultiValued: true},
+		},
+	}
+	new := schema.ReferenceSchema{
+		ID: "urn:example:params:scim:schemas:extension:employee:2.0:User",
+		Attributes: []*schema.Attribute{
+			{Name: "employeeNumber", Type: schema.StringType, Mutability: schema.Immutable},
+			{Name: "badges", Type: schema.StringType, MultiValued: true, Description: "Badge numbers."},
+			{Name: "office", Type: schema.StringType},
+		},
+	}
+
+	changes := schema.Compare(old, new)
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	fmt.Println(changes.Breaking())
+
+	raw, _ := json.Marshal(changes[1])
+	fmt.Println(string(raw))
+
+	// Output:
+	// compatible: badges description changed from  to Badge numbers.
+	// breaking: employeeNumber mutability changed from readWrite to immutable
+	// compatible: office added
+	// true
+	// {"path":"employeeNumber","kind":"changed","characteristic":"mutability","old":"readWrite","new":"immutable","breaking":true}
+}
+
+func TestCompare(t *testing.T) {
+	base := func() *schema.Attribute {
+		return &schema.Attribute{
+			Name:            "type",
+			Type:            schema.StringType,
+			CanonicalValues: []string{"work", "home"},
+			Mutability:      schema.ReadOnly,
+			Returned:        schema.Default,
+			Uniqueness:      schema.Server,
+		}
+	}
+	for _, test := range []struct {
+		name     string
+		change   func(a *schema.Attribute)
+		breaking bool
+	}{
+		{"type", func(a *schema.Attribute) { a.Type = schema.IntegerType }, true},
+		{"multi valued", func(a *schema.Attribute) { a.MultiValued = true }, true},
+		{"required", func(a *schema.Attribute) { a.Required = true }, true},
+		{"case exact", func(a *schema.Attribute) { a.CaseExact = true }, true},
+		{"returned", func(a *schema.Attribute) { a.Returned = schema.Request }, true},
+		{"returned always", func(a *schema.Attribute) { a.Returned = schema.Always }, false},
+		{"uniqueness", func(a *schema.Attribute) { a.Uniqueness = schema.Global }, true},
+		{"uniqueness none", func(a *schema.Attribute) { a.Uniqueness = "" }, false},
+		{"mutability", func(a *schema.Attribute) { a.Mutability = schema.ReadWrite }, false},
+		{"write only", func(a *schema.Attribute) { a.Mutability = schema.WriteOnly }, true},
+		{"canonical value removed", func(a *schema.Attribute) { a.CanonicalValues = []string{"work"} }, true},
+		{"canonical value added", func(a *schema.Attribute) { a.CanonicalValues = []string{"work", "home", "other"} }, false},
+		{"canonical values removed", func(a *schema.Attribute) { a.CanonicalValues = nil }, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			n := base()
+			test.change(n)
+			changes := schema.Compare(
+				schema.ReferenceSchema{ID: "urn:example", Attributes: []*schema.Attribute{base()}},
+				schema.ReferenceSchema{ID: "urn:example", Attributes: []*schema.Attribute{n}},
+			)
+			if len(changes) != 1 {
+				t.Fatalf("expected a single change, got %v", changes)
+			}
+			if changes.Breaking() != test.breaking {
+				t.Errorf("expected breaking to be %v: %s", test.breaking, changes[0])
+			}
+		})
+	}
+}
+
+func TestCompare_attributes(t *testing.T) {
+	old := schema.ReferenceSchema{
+		ID: "urn:example",
+		Attributes: []*schema.Attribute{
+			{Name: "name", Type: schema.ComplexType, SubAttributes: []*schema.Attribute{
+				{Name: "givenName"},
+				{Name: "familyName"},
+			}},
+		},
+	}
+	new := schema.ReferenceSchema{
+		ID: "urn:example",
+		Attributes: []*schema.Attribute{
+			{Name: "Name", Type: schema.ComplexType, SubAttributes: []*schema.Attribute{
+				{Name: "givenName", Type: schema.StringType},
+				{Name: "middleName", Required: true},
+			}},
+			{Name: "id", Required: true, Mutability: schema.ReadOnly},
+		},
+	}
+
+	changes := schema.Compare(old, new)
+	expected := []string{
+		"breaking: Name.familyName removed",
+		"breaking: Name.middleName added",
+		"compatible: id added",
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %v", len(expected), changes)
+	}
+	for i, change := range changes {
+		if change.String() != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], change)
+		}
+	}
+
+	if changes := schema.Compare(schema.UserSchema, schema.UserSchema); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
@@ -0,0 +1,62 @@
+package schema
+
+import "encoding/json"
+
+// ServiceProviderConfigSchema is the URN of the schema of a ServiceProviderConfig.
+const ServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+// ServiceProviderConfig describes the SCIM specification features available on a service provider, as defined in RFC 7643 §5.
+type ServiceProviderConfig struct {
+	DocumentationURI      string                 `json:"documentationUri,omitempty"`
+	Patch                 Supported              `json:"patch"`
+	Bulk                  BulkSupport            `json:"bulk"`
+	Filter                FilterSupport          `json:"filter"`
+	ChangePassword        Supported              `json:"changePassword"`
+	Sort                  Supported              `json:"sort"`
+	ETag                  Supported              `json:"etag"`
+	AuthenticationSchemes []AuthenticationScheme `json:"authenticationSchemes"`
+}
+
+// Supported specifies whether an operation is supported.
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+// BulkSupport specifies bulk configuration options.
+type BulkSupport struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+// FilterSupport specifies filter options.
+type FilterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+// AuthenticationScheme is an authentication scheme supported by the service provider.
+type AuthenticationScheme struct {
+	Type             string `json:"type"`
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	SpecURI          string `json:"specUri,omitempty"`
+	DocumentationURI string `json:"documentationUri,omitempty"`
+	Primary          bool   `json:"primary,omitempty"`
+}
+
+// MarshalJSON encodes the configuration, including its "schemas" attribute.
+// The authentication schemes are always encoded as an array, since the attribute is required.
+func (c ServiceProviderConfig) MarshalJSON() ([]byte, error) {
+	type serviceProviderConfig ServiceProviderConfig
+	if c.AuthenticationSchemes == nil {
+		c.AuthenticationSchemes = []AuthenticationScheme{}
+	}
+	return json.Marshal(struct {
+		Schemas []string `json:"schemas"`
+		serviceProviderConfig
+	}{
+		Schemas:               []string{ServiceProviderConfigSchema},
+		serviceProviderConfig: serviceProviderConfig(c),
+	})
+}
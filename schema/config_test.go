@@ -0,0 +1,84 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/memsql/scimtools/schema"
+)
+
+func ExampleServiceProviderConfig() {
+	raw, _ := json.Marshal(schema.ServiceProviderConfig{
+		Patch:  schema.Supported{Supported: true},
+		Filter: schema.FilterSupport{Supported: true, MaxResults: 200},
+	})
+	fmt.Println(string(raw))
+
+	// Output:
+	// {"schemas":["urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"],"patch":{"supported":true},"bulk":{"supported":false,"maxOperations":0,"maxPayloadSize":0},"filter":{"supported":true,"maxResults":200},"changePassword":{"supported":false},"sort":{"supported":false},"etag":{"supported":false},"authenticationSchemes":[]}
+}
+
+func TestServiceProviderConfig(t *testing.T) {
+	// RFC 7643 §8.5
+	raw := `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"],
+		"documentationUri": "http://example.com/help/scim.html",
+		"patch": {"supported": true},
+		"bulk": {"supported": true, "maxOperations": 1000, "maxPayloadSize": 1048576},
+		"filter": {"supported": true, "maxResults": 200},
+		"changePassword": {"supported": true},
+		"sort": {"supported": true},
+		"etag": {"supported": true},
+		"authenticationSchemes": [
+			{
+				"name": "OAuth Bearer Token",
+				"description": "Authentication scheme using the OAuth Bearer Token Standard",
+				"specUri": "http://www.rfc-editor.org/info/rfc6750",
+				"documentationUri": "http://example.com/help/oauth.html",
+				"type": "oauthbearertoken",
+				"primary": true
+			}
+		]
+	}`
+	var c schema.ServiceProviderConfig
+	if err := json.Unmarshal([]byte(raw), &c); err != nil {
+		t.Fatal(err)
+	}
+	expected := schema.ServiceProviderConfig{
+		DocumentationURI: "http://example.com/help/scim.html",
+		Patch:            schema.Supported{Supported: true},
+		Bulk:             schema.BulkSupport{Supported: true, MaxOperations: 1000, MaxPayloadSize: 1048576},
+		Filter:           schema.FilterSupport{Supported: true, MaxResults: 200},
+		ChangePassword:   schema.Supported{Supported: true},
+		Sort:             schema.Supported{Supported: true},
+		ETag:             schema.Supported{Supported: true},
+		AuthenticationSchemes: []schema.AuthenticationScheme{
+			{
+				Type:             "oauthbearertoken",
+				Name:             "OAuth Bearer Token",
+				Description:      "Authentication scheme using the OAuth Bearer Token Standard",
+				SpecURI:          "http://www.rfc-editor.org/info/rfc6750",
+				DocumentationURI: "http://example.com/help/oauth.html",
+				Primary:          true,
+			},
+		},
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("expected %v, got %v", expected, c)
+	}
+
+	// Encoding and decoding again results in the same configuration.
+	encoded, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded schema.ServiceProviderConfig
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("expected %v, got %v", expected, decoded)
+	}
+}
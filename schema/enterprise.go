@@ -0,0 +1,84 @@
+package schema
+
+// EnterpriseUserSchema is the Enterprise User extension schema as defined in RFC 7643 §8.7.1.
+var EnterpriseUserSchema = ReferenceSchema{
+	ID:          "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+	Name:        "EnterpriseUser",
+	Description: "Enterprise User",
+	Attributes: []*Attribute{
+		{
+			Description: "Numeric or alphanumeric identifier assigned to a person, typically based on order of hire or association with an organization.",
+			Mutability:  ReadWrite,
+			Name:        "employeeNumber",
+			Returned:    Default,
+			Type:        StringType,
+			Uniqueness:  None,
+		},
+		{
+			Description: "Identifies the name of a cost center.",
+			Mutability:  ReadWrite,
+			Name:        "costCenter",
+			Returned:    Default,
+			Type:        StringType,
+			Uniqueness:  None,
+		},
+		{
+			Description: "Identifies the name of an organization.",
+			Mutability:  ReadWrite,
+			Name:        "organization",
+			Returned:    Default,
+			Type:        StringType,
+			Uniqueness:  None,
+		},
+		{
+			Description: "Identifies the name of a division.",
+			Mutability:  ReadWrite,
+			Name:        "division",
+			Returned:    Default,
+			Type:        StringType,
+			Uniqueness:  None,
+		},
+		{
+			Description: "Identifies the name of a department.",
+			Mutability:  ReadWrite,
+			Name:        "department",
+			Returned:    Default,
+			Type:        StringType,
+			Uniqueness:  None,
+		},
+		{
+			Description: "The User's manager.  A complex type that optionally allows service providers to represent organizational hierarchy by referencing the 'id' attribute of another User.",
+			Mutability:  ReadWrite,
+			Name:        "manager",
+			Returned:    Default,
+			SubAttributes: []*Attribute{
+				{
+					Description: "The id of the SCIM resource representing the User's manager.  REQUIRED.",
+					Mutability:  ReadWrite,
+					Name:        "value",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description:    "The URI of the SCIM resource representing the User's manager.  REQUIRED.",
+					Mutability:     ReadWrite,
+					Name:           "$ref",
+					ReferenceTypes: []string{"User"},
+					Returned:       Default,
+					Type:           ReferenceType,
+					Uniqueness:     None,
+				},
+				{
+					Description: "The displayName of the User's manager.  OPTIONAL and READ-ONLY.",
+					Mutability:  ReadOnly,
+					Name:        "displayName",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+			},
+			Type: ComplexType,
+		},
+	},
+}
@@ -0,0 +1,54 @@
+package schema
+
+// GroupSchema is the Group resource schema as defined in RFC 7643 §8.7.1.
+var GroupSchema = ReferenceSchema{
+	ID:          "urn:ietf:params:scim:schemas:core:2.0:Group",
+	Name:        "Group",
+	Description: "Group",
+	Attributes: []*Attribute{
+		{
+			Description: "A human-readable name for the Group.  REQUIRED.",
+			Mutability:  ReadWrite,
+			Name:        "displayName",
+			Returned:    Default,
+			Type:        StringType,
+			Uniqueness:  None,
+		},
+		{
+			Description: "A list of members of the Group.",
+			MultiValued: true,
+			Mutability:  ReadWrite,
+			Name:        "members",
+			Returned:    Default,
+			SubAttributes: []*Attribute{
+				{
+					Description: "Identifier of the member of this Group.",
+					Mutability:  Immutable,
+					Name:        "value",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description:    "The URI corresponding to a SCIM resource that is a member of this Group.",
+					Mutability:     Immutable,
+					Name:           "$ref",
+					ReferenceTypes: []string{"User", "Group"},
+					Returned:       Default,
+					Type:           ReferenceType,
+					Uniqueness:     None,
+				},
+				{
+					CanonicalValues: []string{"User", "Group"},
+					Description:     "A label indicating the type of resource, e.g., 'User' or 'Group'.",
+					Mutability:      Immutable,
+					Name:            "type",
+					Returned:        Default,
+					Type:            StringType,
+					Uniqueness:      None,
+				},
+			},
+			Type: ComplexType,
+		},
+	},
+}
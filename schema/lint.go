@@ -0,0 +1,114 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LintError describes a problem with the definition of a schema.
+type LintError struct {
+	// Schema is the ID of the schema.
+	Schema string
+	// Path is the (dotted) name of the attribute, empty if the problem concerns the schema itself.
+	Path   string
+	Detail string
+}
+
+func (e *LintError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%s: %s", e.Schema, e.Detail)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Schema, e.Path, e.Detail)
+}
+
+// LintErrors is a list of problems with schema definitions.
+type LintErrors []*LintError
+
+func (e LintErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Lint checks the definition of the schema. It reports unknown types and characteristics, sub attributes of
+// attributes that are not complex, canonical values of attributes that are not strings, reference attributes
+// without reference types and attribute names that are not unique (case insensitive).
+// Returns LintErrors, in the order of the attributes, if the definition is invalid.
+func Lint(s ReferenceSchema) error {
+	l := linter{schema: s.ID}
+	if s.ID == "" {
+		l.errorf("", "schema has no id")
+	}
+	l.lintAttributes("", s.Attributes)
+	if len(l.errs) == 0 {
+		return nil
+	}
+	return l.errs
+}
+
+type linter struct {
+	schema string
+	errs   LintErrors
+}
+
+func (l *linter) errorf(path, format string, args ...interface{}) {
+	l.errs = append(l.errs, &LintError{
+		Schema: l.schema,
+		Path:   path,
+		Detail: fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) lintAttributes(prefix string, attrs []*Attribute) {
+	names := make(map[string]bool)
+	for _, attribute := range attrs {
+		path := prefix + attribute.Name
+		if attribute.Name == "" {
+			l.errorf(path, "attribute has no name")
+		} else if name := strings.ToLower(attribute.Name); names[name] {
+			l.errorf(path, "duplicate attribute name")
+		} else {
+			names[name] = true
+		}
+		l.lintAttribute(path, attribute)
+	}
+}
+
+func (l *linter) lintAttribute(path string, attribute *Attribute) {
+	switch attribute.Type {
+	case "", StringType, BooleanType, BinaryType, DecimalType, IntegerType, DateTimeType, ReferenceType, ComplexType:
+	default:
+		l.errorf(path, "unknown type %q", attribute.Type)
+	}
+	switch attribute.Mutability {
+	case "", ReadOnly, ReadWrite, Immutable, WriteOnly:
+	default:
+		l.errorf(path, "unknown mutability %q", attribute.Mutability)
+	}
+	switch attribute.Returned {
+	case "", Always, Never, Default, Request:
+	default:
+		l.errorf(path, "unknown returned %q", attribute.Returned)
+	}
+	switch attribute.Uniqueness {
+	case "", None, Server, Global:
+	default:
+		l.errorf(path, "unknown uniqueness %q", attribute.Uniqueness)
+	}
+
+	if len(attribute.CanonicalValues) != 0 && attribute.Type != "" && attribute.Type != StringType {
+		l.errorf(path, "canonical values are only allowed on string attributes")
+	}
+	if attribute.Type == ReferenceType && len(attribute.ReferenceTypes) == 0 {
+		l.errorf(path, "reference attribute has no reference types")
+	}
+	if attribute.Type != ComplexType {
+		if len(attribute.SubAttributes) != 0 {
+			l.errorf(path, "sub attributes are only allowed on complex attributes")
+		}
+		return
+	}
+	l.lintAttributes(path+".", attribute.SubAttributes)
+}
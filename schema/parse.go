@@ -0,0 +1,56 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+)
+
+// Parse decodes the JSON representation of one or more schemas, as returned by the "/Schemas" endpoint.
+// The data can either be a single schema, an array of schemas or a ListResponse containing schemas.
+// All the schemas are linted, returns LintErrors if any of the definitions is invalid.
+func Parse(data []byte) ([]ReferenceSchema, error) {
+	data = bytes.TrimSpace(data)
+
+	var schemas []ReferenceSchema
+	if len(data) != 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &schemas); err != nil {
+			return nil, err
+		}
+	} else {
+		var list struct {
+			Resources []ReferenceSchema `json:"Resources"`
+		}
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, err
+		}
+		schemas = list.Resources
+		if list.Resources == nil {
+			var s ReferenceSchema
+			if err := json.Unmarshal(data, &s); err != nil {
+				return nil, err
+			}
+			schemas = []ReferenceSchema{s}
+		}
+	}
+
+	var errs LintErrors
+	for _, s := range schemas {
+		if err := Lint(s); err != nil {
+			errs = append(errs, err.(LintErrors)...)
+		}
+	}
+	if len(errs) != 0 {
+		return nil, errs
+	}
+	return schemas, nil
+}
+
+// LoadFile reads the schemas from the given JSON file, see Parse.
+func LoadFile(path string) ([]ReferenceSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
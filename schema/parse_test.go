@@ -0,0 +1,116 @@
+package schema_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/memsql/scimtools/schema"
+)
+
+func ExampleLoadFile() {
+	schemas, err := schema.LoadFile("testdata/schemas.json")
+	if err != nil {
+		panic(err)
+	}
+	for _, s := range schemas {
+		fmt.Println(s.ID, len(s.Attributes))
+	}
+
+	// Output:
+	// urn:ietf:params:scim:schemas:core:2.0:Group 2
+	// urn:ietf:params:scim:schemas:extension:enterprise:2.0:User 1
+}
+
+func ExampleLint() {
+	err := schema.Lint(schema.ReferenceSchema{
+		ID: "urn:example:params:scim:schemas:core:2.0:Device",
+		Attributes: []*schema.Attribute{
+			{Name: "serial", Type: "text"},
+			{Name: "owner", Type: schema.ReferenceType},
+			{Name: "Serial", Type: schema.StringType, Mutability: "writeOnce"},
+		},
+	})
+	var errs schema.LintErrors
+	if errors.As(err, &errs) {
+		for _, err := range errs {
+			fmt.Println(err)
+		}
+	}
+
+	// Output:
+	// urn:example:params:scim:schemas:core:2.0:Device: serial: unknown type "text"
+	// urn:example:params:scim:schemas:core:2.0:Device: owner: reference attribute has no reference types
+	// urn:example:params:scim:schemas:core:2.0:Device: Serial: duplicate attribute name
+	// urn:example:params:scim:schemas:core:2.0:Device: Serial: unknown mutability "writeOnce"
+}
+
+func TestParse(t *testing.T) {
+	for _, data := range []string{
+		`{"id": "urn:example:Device", "attributes": [{"name": "serial", "type": "string"}]}`,
+		`[{"id": "urn:example:Device", "attributes": [{"name": "serial", "type": "string"}]}]`,
+		`{"totalResults": 1, "Resources": [{"id": "urn:example:Device", "attributes": [{"name": "serial", "type": "string"}]}]}`,
+	} {
+		schemas, err := schema.Parse([]byte(data))
+		if err != nil {
+			t.Errorf("%s: %s", data, err)
+			continue
+		}
+		if len(schemas) != 1 || schemas[0].ID != "urn:example:Device" || schemas[0].Attributes[0].Name != "serial" {
+			t.Errorf("%s: unexpected schemas %v", data, schemas)
+		}
+	}
+
+	if _, err := schema.Parse([]byte(`{"id": 1}`)); err == nil {
+		t.Error("error expected, got none")
+	}
+	if _, err := schema.LoadFile("testdata/unknown.json"); err == nil {
+		t.Error("error expected, got none")
+	}
+}
+
+func TestLint(t *testing.T) {
+	for _, s := range []schema.ReferenceSchema{
+		schema.UserSchema,
+		schema.GroupSchema,
+		schema.EnterpriseUserSchema,
+	} {
+		if err := schema.Lint(s); err != nil {
+			t.Errorf("%s: %s", s.Name, err)
+		}
+	}
+
+	// Characteristics that are not present have their default value, including the type (string).
+	if err := schema.Lint(schema.ReferenceSchema{ID: "urn:example:Device", Attributes: []*schema.Attribute{
+		{Name: "serial", CanonicalValues: []string{"A", "B"}},
+	}}); err != nil {
+		t.Error(err)
+	}
+
+	for _, test := range []struct {
+		name      string
+		attribute *schema.Attribute
+	}{
+		{"type", &schema.Attribute{Name: "a", Type: "text"}},
+		{"sub attributes", &schema.Attribute{Name: "a", Type: schema.StringType, SubAttributes: []*schema.Attribute{
+			{Name: "b", Type: schema.StringType},
+		}}},
+		{"canonical values", &schema.Attribute{Name: "a", Type: schema.IntegerType, CanonicalValues: []string{"1"}}},
+		{"mutability", &schema.Attribute{Name: "a", Type: schema.StringType, Mutability: "writeOnce"}},
+		{"returned", &schema.Attribute{Name: "a", Type: schema.StringType, Returned: "sometimes"}},
+		{"uniqueness", &schema.Attribute{Name: "a", Type: schema.StringType, Uniqueness: "local"}},
+		{"reference types", &schema.Attribute{Name: "a", Type: schema.ReferenceType}},
+		{"duplicate sub attribute", &schema.Attribute{Name: "a", Type: schema.ComplexType, SubAttributes: []*schema.Attribute{
+			{Name: "b", Type: schema.StringType},
+			{Name: "B", Type: schema.StringType},
+		}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := schema.Lint(schema.ReferenceSchema{ID: "urn:example:Device", Attributes: []*schema.Attribute{test.attribute}})
+			var errs schema.LintErrors
+			if !errors.As(err, &errs) || len(errs) != 1 {
+				t.Errorf("expected a single lint error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Registry holds reference schemas by their URN and resource types by their ID.
+// All lookups are case insensitive.
+type Registry struct {
+	schemas       map[string]ReferenceSchema
+	resourceTypes map[string]ResourceType
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		schemas:       make(map[string]ReferenceSchema),
+		resourceTypes: make(map[string]ResourceType),
+	}
+}
+
+// AddSchema adds the reference schema to the registry.
+// Returns an error if the schema has no ID, or a schema with the same ID was already added.
+func (r *Registry) AddSchema(s ReferenceSchema) error {
+	if s.ID == "" {
+		return fmt.Errorf("schema %q has no id", s.Name)
+	}
+	key := strings.ToLower(s.ID)
+	if _, ok := r.schemas[key]; ok {
+		return fmt.Errorf("duplicate schema %q", s.ID)
+	}
+	r.schemas[key] = s
+	return nil
+}
+
+// AddResourceType adds the resource type to the registry.
+// Returns an error if the resource type has no ID, a resource type with the same ID was already added,
+// or the schema (or any of its extensions) has not been added to the registry.
+func (r *Registry) AddResourceType(t ResourceType) error {
+	if t.ID == "" {
+		return fmt.Errorf("resource type %q has no id", t.Name)
+	}
+	key := strings.ToLower(t.ID)
+	if _, ok := r.resourceTypes[key]; ok {
+		return fmt.Errorf("duplicate resource type %q", t.ID)
+	}
+	if _, ok := r.Schema(t.Schema); !ok {
+		return fmt.Errorf("resource type %q: unknown schema %q", t.ID, t.Schema)
+	}
+	for _, e := range t.SchemaExtensions {
+		if _, ok := r.Schema(e.Schema); !ok {
+			return fmt.Errorf("resource type %q: unknown schema extension %q", t.ID, e.Schema)
+		}
+	}
+	r.resourceTypes[key] = t
+	return nil
+}
+
+// Schema returns the reference schema with the given URN.
+func (r *Registry) Schema(id string) (ReferenceSchema, bool) {
+	s, ok := r.schemas[strings.ToLower(id)]
+	return s, ok
+}
+
+// ResourceType returns the resource type with the given ID.
+func (r *Registry) ResourceType(id string) (ResourceType, bool) {
+	t, ok := r.resourceTypes[strings.ToLower(id)]
+	return t, ok
+}
+
+// ResourceSchemas returns the schema and all the schema extensions of the resource type with the given ID.
+func (r *Registry) ResourceSchemas(id string) (ReferenceSchema, []ReferenceSchema, error) {
+	t, ok := r.ResourceType(id)
+	if !ok {
+		return ReferenceSchema{}, nil, fmt.Errorf("unknown resource type %q", id)
+	}
+	s, _ := r.Schema(t.Schema)
+	var extensions []ReferenceSchema
+	for _, e := range t.SchemaExtensions {
+		extension, _ := r.Schema(e.Schema)
+		extensions = append(extensions, extension)
+	}
+	return s, extensions, nil
+}
+
+// Resolve resolves the "schemas" attribute of a resource into the resource type, its core schema and the extensions
+// it contains. Returns an error if the URNs do not match exactly one resource type, one of the URNs is not an
+// extension of that resource type, or a required extension is missing.
+func (r *Registry) Resolve(schemas []string) (ResourceType, ReferenceSchema, []ReferenceSchema, error) {
+	var matches []ResourceType
+	for _, t := range r.sortedResourceTypes() {
+		for _, id := range schemas {
+			if strings.EqualFold(id, t.Schema) {
+				matches = append(matches, t)
+				break
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return ResourceType{}, ReferenceSchema{}, nil, fmt.Errorf("no resource type matches the schemas %v", schemas)
+	case 1:
+	default:
+		return ResourceType{}, ReferenceSchema{}, nil, fmt.Errorf("multiple resource types match the schemas %v", schemas)
+	}
+
+	t := matches[0]
+	s, _ := r.Schema(t.Schema)
+	var extensions []ReferenceSchema
+	for _, id := range schemas {
+		if strings.EqualFold(id, t.Schema) {
+			continue
+		}
+		extension, ok := t.extension(id)
+		if !ok {
+			return ResourceType{}, ReferenceSchema{}, nil, fmt.Errorf("%q is not a schema extension of resource type %q", id, t.ID)
+		}
+		e, _ := r.Schema(extension.Schema)
+		extensions = append(extensions, e)
+	}
+	for _, e := range t.SchemaExtensions {
+		if !e.Required {
+			continue
+		}
+		if !containsFold(schemas, e.Schema) {
+			return ResourceType{}, ReferenceSchema{}, nil, fmt.Errorf("required schema extension %q is missing", e.Schema)
+		}
+	}
+	return t, s, extensions, nil
+}
+
+// Attribute returns the attribute with the given name of the resource type, together with the schema that defines it.
+// The name can either be fully qualified (e.g. "urn:ietf:params:scim:schemas:core:2.0:User:name.givenName")
+// or relative to the resource (e.g. "name.givenName"). Names without URN are looked up in the core schema first,
+// returns an error if they are defined by multiple schema extensions instead.
+func (r *Registry) Attribute(resourceType, name string) (*Attribute, ReferenceSchema, error) {
+	s, extensions, err := r.ResourceSchemas(resourceType)
+	if err != nil {
+		return nil, ReferenceSchema{}, err
+	}
+
+	uri, path := splitURI(name)
+	if uri != "" {
+		for _, candidate := range append([]ReferenceSchema{s}, extensions...) {
+			if !strings.EqualFold(uri, candidate.ID) {
+				continue
+			}
+			if attribute := findPath(path, candidate.Attributes); attribute != nil {
+				return attribute, candidate, nil
+			}
+			return nil, ReferenceSchema{}, fmt.Errorf("unknown attribute %q", name)
+		}
+		return nil, ReferenceSchema{}, fmt.Errorf("schema %q is not part of resource type %q", uri, resourceType)
+	}
+
+	if attribute := findPath(path, s.Attributes); attribute != nil {
+		return attribute, s, nil
+	}
+	if attribute := findPath(path, CoreAttributes); attribute != nil {
+		return attribute, s, nil
+	}
+	var (
+		attribute *Attribute
+		owner     ReferenceSchema
+	)
+	for _, e := range extensions {
+		if a := findPath(path, e.Attributes); a != nil {
+			if attribute != nil {
+				return nil, ReferenceSchema{}, fmt.Errorf("attribute %q is defined by both %q and %q", name, owner.ID, e.ID)
+			}
+			attribute, owner = a, e
+		}
+	}
+	if attribute == nil {
+		return nil, ReferenceSchema{}, fmt.Errorf("unknown attribute %q", name)
+	}
+	return attribute, owner, nil
+}
+
+// Conflicts returns the names of the top level attributes that are defined by more than one of the schemas of the
+// resource type, in sorted order.
+func (r *Registry) Conflicts(resourceType string) ([]string, error) {
+	s, extensions, err := r.ResourceSchemas(resourceType)
+	if err != nil {
+		return nil, err
+	}
+	defined := make(map[string]int)
+	for _, candidate := range append([]ReferenceSchema{s}, extensions...) {
+		for _, attribute := range candidate.Attributes {
+			defined[strings.ToLower(attribute.Name)]++
+		}
+	}
+	var conflicts []string
+	for name, n := range defined {
+		if n > 1 {
+			conflicts = append(conflicts, name)
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts, nil
+}
+
+func (r *Registry) sortedResourceTypes() []ResourceType {
+	types := make([]ResourceType, 0, len(r.resourceTypes))
+	for _, t := range r.resourceTypes {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].ID < types[j].ID
+	})
+	return types
+}
+
+func (t ResourceType) extension(id string) (SchemaExtension, bool) {
+	for _, e := range t.SchemaExtensions {
+		if strings.EqualFold(id, e.Schema) {
+			return e, true
+		}
+	}
+	return SchemaExtension{}, false
+}
+
+// splitURI splits a fully qualified attribute name into the URN of the schema and the attribute path.
+func splitURI(name string) (string, string) {
+	i := strings.LastIndex(name, ":")
+	if i == -1 {
+		return "", name
+	}
+	return name[:i], name[i+1:]
+}
+
+// findPath returns the (sub) attribute the path (e.g. "name.givenName") refers to, nil if not found.
+func findPath(path string, attrs []*Attribute) *Attribute {
+	name, sub := path, ""
+	if i := strings.Index(path, "."); i != -1 {
+		name, sub = path[:i], path[i+1:]
+	}
+	for _, attribute := range attrs {
+		if !strings.EqualFold(name, attribute.Name) {
+			continue
+		}
+		if sub == "" {
+			return attribute
+		}
+		if attribute.Type != ComplexType {
+			return nil
+		}
+		return findPath(sub, attribute.SubAttributes)
+	}
+	return nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package schema_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/memsql/scimtools/schema"
+)
+
+var employeeSchema = schema.ReferenceSchema{
+	ID:   "urn:example:params:scim:schemas:extension:employee:2.0:User",
+	Name: "Employee",
+	Attributes: []*schema.Attribute{
+		{Name: "employeeNumber", Type: schema.StringType},
+		{Name: "title", Type: schema.StringType},
+	},
+}
+
+func newRegistry(t testing.TB) *schema.Registry {
+	r := schema.NewRegistry()
+	for _, s := range []schema.ReferenceSchema{
+		schema.UserSchema,
+		schema.GroupSchema,
+		schema.EnterpriseUserSchema,
+		employeeSchema,
+	} {
+		if err := r.AddSchema(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, rt := range []schema.ResourceType{
+		{
+			ID:       "User",
+			Name:     "User",
+			Endpoint: "/Users",
+			Schema:   schema.UserSchema.ID,
+			SchemaExtensions: []schema.SchemaExtension{
+				{Schema: schema.EnterpriseUserSchema.ID, Required: true},
+				{Schema: employeeSchema.ID},
+			},
+		},
+		{ID: "Group", Name: "Group", Endpoint: "/Groups", Schema: schema.GroupSchema.ID},
+	} {
+		if err := r.AddResourceType(rt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func ExampleRegistry_Resolve() {
+	r := schema.NewRegistry()
+	_ = r.AddSchema(schema.UserSchema)
+	_ = r.AddSchema(schema.EnterpriseUserSchema)
+	_ = r.AddResourceType(schema.ResourceType{
+		ID:               "User",
+		Name:             "User",
+		Endpoint:         "/Users",
+		Schema:           schema.UserSchema.ID,
+		SchemaExtensions: []schema.SchemaExtension{{Schema: schema.EnterpriseUserSchema.ID}},
+	})
+
+	t, s, extensions, _ := r.Resolve([]string{
+		"urn:ietf:params:scim:schemas:core:2.0:User",
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+	})
+	fmt.Println(t.ID, s.Name, extensions[0].Name)
+
+	attribute, s, _ := r.Attribute("User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value")
+	fmt.Println(attribute.Name, s.Name)
+
+	// Output:
+	// User User EnterpriseUser
+	// value EnterpriseUser
+}
+
+func TestRegistry(t *testing.T) {
+	r := newRegistry(t)
+	if err := r.AddSchema(schema.UserSchema); err == nil {
+		t.Error("error expected for duplicate schema, got none")
+	}
+	if err := r.AddResourceType(schema.ResourceType{ID: "user", Schema: schema.UserSchema.ID}); err == nil {
+		t.Error("error expected for duplicate resource type, got none")
+	}
+	if err := r.AddResourceType(schema.ResourceType{ID: "Device", Schema: "urn:example:Device"}); err == nil {
+		t.Error("error expected for unknown schema, got none")
+	}
+
+	if s, ok := r.Schema("URN:IETF:PARAMS:SCIM:SCHEMAS:CORE:2.0:GROUP"); !ok || s.Name != "Group" {
+		t.Errorf("expected the group schema, got %v", s)
+	}
+	if _, ok := r.ResourceType("Device"); ok {
+		t.Error("unexpected resource type")
+	}
+
+	conflicts, err := r.Conflicts("User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conflicts, []string{"employeenumber", "title"}) {
+		t.Errorf("unexpected conflicts: %v", conflicts)
+	}
+}
+
+func TestRegistry_Resolve(t *testing.T) {
+	r := newRegistry(t)
+	rt, s, extensions, err := r.Resolve([]string{schema.EnterpriseUserSchema.ID, schema.UserSchema.ID, employeeSchema.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt.ID != "User" || s.ID != schema.UserSchema.ID || len(extensions) != 2 || extensions[1].ID != employeeSchema.ID {
+		t.Errorf("unexpected resolution: %s %s %v", rt.ID, s.ID, extensions)
+	}
+
+	for _, schemas := range [][]string{
+		nil,
+		{"urn:example:Device"},
+		{schema.UserSchema.ID},
+		{schema.UserSchema.ID, schema.GroupSchema.ID},
+		{schema.GroupSchema.ID, schema.EnterpriseUserSchema.ID},
+	} {
+		if _, _, _, err := r.Resolve(schemas); err == nil {
+			t.Errorf("%v: error expected, got none", schemas)
+		}
+	}
+}
+
+func TestRegistry_Attribute(t *testing.T) {
+	r := newRegistry(t)
+	for name, expected := range map[string]string{
+		"userName":                            schema.UserSchema.ID,
+		"NAME.givenName":                      schema.UserSchema.ID,
+		"id":                                  schema.UserSchema.ID,
+		"title":                               schema.UserSchema.ID,
+		"costCenter":                          schema.EnterpriseUserSchema.ID,
+		"manager.value":                       schema.EnterpriseUserSchema.ID,
+		employeeSchema.ID + ":title":          employeeSchema.ID,
+		schema.UserSchema.ID + ":emails.type": schema.UserSchema.ID,
+	} {
+		attribute, s, err := r.Attribute("User", name)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if s.ID != expected || attribute == nil {
+			t.Errorf("%s: expected %s, got %s", name, expected, s.ID)
+		}
+	}
+
+	for _, name := range []string{
+		"employeeNumber",
+		"nickName.value",
+		"unknown",
+		schema.GroupSchema.ID + ":displayName",
+		employeeSchema.ID + ":userName",
+	} {
+		if _, _, err := r.Attribute("User", name); err == nil {
+			t.Errorf("%s: error expected, got none", name)
+		}
+	}
+	if _, _, err := r.Attribute("Device", "id"); err == nil {
+		t.Error("error expected for unknown resource type, got none")
+	}
+}
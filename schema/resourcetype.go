@@ -0,0 +1,34 @@
+package schema
+
+import "encoding/json"
+
+// ResourceTypeSchema is the URN of the schema of a ResourceType.
+const ResourceTypeSchema = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+
+// ResourceType specifies the metadata about a resource type, as defined in RFC 7643 §6.
+type ResourceType struct {
+	ID               string            `json:"id,omitempty"`
+	Name             string            `json:"name"`
+	Description      string            `json:"description,omitempty"`
+	Endpoint         string            `json:"endpoint"`
+	Schema           string            `json:"schema"`
+	SchemaExtensions []SchemaExtension `json:"schemaExtensions,omitempty"`
+}
+
+// SchemaExtension is a schema extension of a ResourceType.
+type SchemaExtension struct {
+	Schema   string `json:"schema"`
+	Required bool   `json:"required"`
+}
+
+// MarshalJSON encodes the resource type, including its "schemas" attribute.
+func (r ResourceType) MarshalJSON() ([]byte, error) {
+	type resourceType ResourceType
+	return json.Marshal(struct {
+		Schemas []string `json:"schemas"`
+		resourceType
+	}{
+		Schemas:      []string{ResourceTypeSchema},
+		resourceType: resourceType(r),
+	})
+}
@@ -0,0 +1,57 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/memsql/scimtools/schema"
+)
+
+func ExampleResourceType() {
+	raw, _ := json.Marshal(schema.ResourceType{
+		ID:          "User",
+		Name:        "User",
+		Description: "User Account",
+		Endpoint:    "/Users",
+		Schema:      schema.UserSchema.ID,
+		SchemaExtensions: []schema.SchemaExtension{
+			{Schema: schema.EnterpriseUserSchema.ID, Required: true},
+		},
+	})
+	fmt.Println(string(raw))
+
+	// Output:
+	// {"schemas":["urn:ietf:params:scim:schemas:core:2.0:ResourceType"],"id":"User","name":"User","description":"User Account","endpoint":"/Users","schema":"urn:ietf:params:scim:schemas:core:2.0:User","schemaExtensions":[{"schema":"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User","required":true}]}
+}
+
+func TestResourceType(t *testing.T) {
+	// RFC 7643 §8.6
+	raw := `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:ResourceType"],
+		"id": "Group",
+		"name": "Group",
+		"endpoint": "/Groups",
+		"description": "Group",
+		"schema": "urn:ietf:params:scim:schemas:core:2.0:Group",
+		"meta": {
+			"location": "https://example.com/v2/ResourceTypes/Group",
+			"resourceType": "ResourceType"
+		}
+	}`
+	var r schema.ResourceType
+	if err := json.Unmarshal([]byte(raw), &r); err != nil {
+		t.Fatal(err)
+	}
+	expected := schema.ResourceType{
+		ID:          "Group",
+		Name:        "Group",
+		Description: "Group",
+		Endpoint:    "/Groups",
+		Schema:      schema.GroupSchema.ID,
+	}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("expected %v, got %v", expected, r)
+	}
+}
@@ -1,5 +1,7 @@
 package schema
 
+import "encoding/json"
+
 var (
 	SchemasAttribute = &Attribute{
 		MultiValued: true,
@@ -84,6 +86,46 @@ type Attribute struct {
 	ReferenceTypes  []string     `json:"referenceTypes"`
 }
 
+// UnmarshalJSON decodes the attribute, characteristics that are not present get their default value (RFC 7643 §2.2).
+func (attribute *Attribute) UnmarshalJSON(data []byte) error {
+	type attr Attribute
+	a := attr{
+		Type:       StringType,
+		Mutability: ReadWrite,
+		Returned:   Default,
+		Uniqueness: None,
+	}
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	*attribute = Attribute(a)
+	attribute.normalize()
+	return nil
+}
+
+// Normalize sets the characteristics of the attribute and all its sub attributes that are empty to their default
+// value, as defined in RFC 7643 §2.2. (i.e. type string, mutability readWrite, returned default and uniqueness none)
+func (attribute *Attribute) Normalize() {
+	attribute.ForEachAttribute(func(attribute *Attribute) {
+		attribute.normalize()
+	})
+}
+
+func (attribute *Attribute) normalize() {
+	if attribute.Type == "" {
+		attribute.Type = StringType
+	}
+	if attribute.Mutability == "" {
+		attribute.Mutability = ReadWrite
+	}
+	if attribute.Returned == "" {
+		attribute.Returned = Default
+	}
+	if attribute.Uniqueness == "" {
+		attribute.Uniqueness = None
+	}
+}
+
 // ForEachAttribute calls given function on itself all sub attributes recursively.
 func (attribute *Attribute) ForEachAttribute(f func(attribute *Attribute)) {
 	f(attribute)
@@ -111,6 +153,13 @@ type ReferenceSchema struct {
 	Attributes  []*Attribute `json:"attributes"`
 }
 
+// Normalize sets the empty characteristics of all attributes to their default value, see Attribute.Normalize.
+func (s ReferenceSchema) Normalize() {
+	for _, attribute := range s.Attributes {
+		attribute.Normalize()
+	}
+}
+
 // ForEachAttribute calls given function on all attributes recursively.
 func (s ReferenceSchema) ForEachAttribute(f func(attribute *Attribute)) {
 	for _, attribute := range s.Attributes {
@@ -0,0 +1,77 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/memsql/scimtools/schema"
+)
+
+func ExampleReferenceSchema_Normalize() {
+	s := schema.ReferenceSchema{
+		Attributes: []*schema.Attribute{
+			{
+				Name:       "name",
+				Type:       schema.ComplexType,
+				Mutability: schema.Immutable,
+				SubAttributes: []*schema.Attribute{
+					{Name: "givenName"},
+				},
+			},
+		},
+	}
+	s.Normalize()
+
+	name := s.Attributes[0]
+	fmt.Println(name.Type, name.Mutability, name.Returned, name.Uniqueness)
+	givenName := name.SubAttributes[0]
+	fmt.Println(givenName.Type, givenName.Mutability, givenName.Returned, givenName.Uniqueness)
+
+	// Output:
+	// complex immutable default none
+	// string readWrite default none
+}
+
+func TestAttribute_UnmarshalJSON(t *testing.T) {
+	var attribute schema.Attribute
+	if err := json.Unmarshal([]byte(`{
+		"name": "emails",
+		"type": "complex",
+		"multiValued": true,
+		"returned": "always",
+		"subAttributes": [{"name": "value", "mutability": "readOnly"}]
+	}`), &attribute); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := schema.Attribute{
+		Name:        "emails",
+		Type:        schema.ComplexType,
+		MultiValued: true,
+		Mutability:  schema.ReadWrite,
+		Returned:    schema.Always,
+		Uniqueness:  schema.None,
+		SubAttributes: []*schema.Attribute{
+			{
+				Name:       "value",
+				Type:       schema.StringType,
+				Mutability: schema.ReadOnly,
+				Returned:   schema.Default,
+				Uniqueness: schema.None,
+			},
+		},
+	}
+	if !reflect.DeepEqual(attribute, expected) {
+		t.Errorf("expected %v, got %v", expected, attribute)
+	}
+
+	// Empty characteristics are replaced as well.
+	if err := json.Unmarshal([]byte(`{"name": "value", "type": "", "mutability": ""}`), &attribute); err != nil {
+		t.Fatal(err)
+	}
+	if attribute.Type != schema.StringType || attribute.Mutability != schema.ReadWrite {
+		t.Errorf("expected defaults, got %v", attribute)
+	}
+}
@@ -0,0 +1,575 @@
+package schema
+
+// UserSchema is the User resource schema as defined in RFC 7643 §8.7.1.
+var UserSchema = ReferenceSchema{
+	ID:          "urn:ietf:params:scim:schemas:core:2.0:User",
+	Name:        "User",
+	Description: "User Account",
+	Attributes: []*Attribute{
+		{
+			Description: "Unique identifier for the User, typically used by the user to directly authenticate to the service provider.  Each User MUST include a non-empty userName value.  This identifier MUST be unique across the service provider's entire set of Users.  REQUIRED.",
+			Mutability:  ReadWrite,
+			Name:        "userName",
+			Required:    true,
+			Returned:    Default,
+			Type:        StringType,
+			Uniqueness:  Server,
+		},
+		{
+			Description: "The components of the user's real name.  Providers MAY return just the full name as a single string in the formatted sub-attribute, or they MAY return just the individual component attributes using the other sub-attributes, or they MAY return both.  If both variants are returned, they SHOULD be describing the same name, with the formatted name indicating how the component attributes should be combined.",
+			Mutability:  ReadWrite,
+			Name:        "name",
+			Returned:    Default,
+			SubAttributes: []*Attribute{
+				{
+					Description: "The full name, including all middle names, titles, and suffixes as appropriate, formatted for display (e.g., 'Ms. Barbara J Jensen, III').",
+					Mutability:  ReadWrite,
+					Name:        "formatted",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "The family name of the User, or last name in most Western languages (e.g., 'Jensen' given the full name 'Ms. Barbara J Jensen, III').",
+					Mutability:  ReadWrite,
+					Name:        "familyName",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "The given name of the User, or first name in most Western languages (e.g., 'Barbara' given the full name 'Ms. Barbara J Jensen, III').",
+					Mutability:  ReadWrite,
+					Name:        "givenName",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "The middle name(s) of the User (e.g., 'Jane' given the full name 'Ms. Barbara J Jensen, III').",
+					Mutability:  ReadWrite,
+					Name:        "middleName",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "The honorific prefix(es) of the User, or title in most Western languages (e.g., 'Ms.' given the full name 'Ms. Barbara J Jensen, III').",
+					Mutability:  ReadWrite,
+					Name:        "honorificPrefix",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "The honorific suffix(es) of the User, or suffix in most Western languages (e.g., 'III' given the full name 'Ms. Barbara J Jensen, III').",
+					Mutability:  ReadWrite,
+					Name:        "honorificSuffix",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+			},
+			Type: ComplexType,
+		},
+		{
+			Description: "The name of the User, suitable for display to end-users.  The name SHOULD be the full name of the User being described, if known.",
+			Mutability:  ReadWrite,
+			Name:        "displayName",
+			Returned:    Default,
+			Type:        StringType,
+			Uniqueness:  None,
+		},
+		{
+			Description: "The casual way to address the user in real life, e.g., 'Bob' or 'Bobby' instead of 'Robert'.  This attribute SHOULD NOT be used to represent a User's username (e.g., 'bjensen' or 'mpepperidge').",
+			Mutability:  ReadWrite,
+			Name:        "nickName",
+			Returned:    Default,
+			Type:        StringType,
+			Uniqueness:  None,
+		},
+		{
+			Description:    "A fully qualified URL pointing to a page representing the User's online profile.",
+			Mutability:     ReadWrite,
+			Name:           "profileUrl",
+			ReferenceTypes: []string{"external"},
+			Returned:       Default,
+			Type:           ReferenceType,
+			Uniqueness:     None,
+		},
+		{
+			Description: "The user's title, such as \"Vice President.\"",
+			Mutability:  ReadWrite,
+			Name:        "title",
+			Returned:    Default,
+			Type:        StringType,
+			Uniqueness:  None,
+		},
+		{
+			Description: "Used to identify the relationship between the organization and the user.  Typical values used might be 'Contractor', 'Employee', 'Intern', 'Temp', 'External', and 'Unknown', but any value may be used.",
+			Mutability:  ReadWrite,
+			Name:        "userType",
+			Returned:    Default,
+			Type:        StringType,
+			Uniqueness:  None,
+		},
+		{
+			Description: "Indicates the User's preferred written or spoken language.  Generally used for selecting a localized user interface; e.g., 'en_US' specifies the language English and country US.",
+			Mutability:  ReadWrite,
+			Name:        "preferredLanguage",
+			Returned:    Default,
+			Type:        StringType,
+			Uniqueness:  None,
+		},
+		{
+			Description: "Used to indicate the User's default location for purposes of localizing items such as currency, date time format, or numerical representations.",
+			Mutability:  ReadWrite,
+			Name:        "locale",
+			Returned:    Default,
+			Type:        StringType,
+			Uniqueness:  None,
+		},
+		{
+			Description: "The User's time zone in the 'Olson' time zone database format, e.g., 'America/Los_Angeles'.",
+			Mutability:  ReadWrite,
+			Name:        "timezone",
+			Returned:    Default,
+			Type:        StringType,
+			Uniqueness:  None,
+		},
+		{
+			Description: "A Boolean value indicating the User's administrative status.",
+			Mutability:  ReadWrite,
+			Name:        "active",
+			Returned:    Default,
+			Type:        BooleanType,
+			Uniqueness:  None,
+		},
+		{
+			Description: "The User's cleartext password.  This attribute is intended to be used as a means to specify an initial password when creating a new User or to reset an existing User's password.",
+			Mutability:  WriteOnly,
+			Name:        "password",
+			Returned:    Never,
+			Type:        StringType,
+			Uniqueness:  None,
+		},
+		{
+			Description: "Email addresses for the user.  The value SHOULD be canonicalized by the service provider, e.g., 'bjensen@example.com' instead of 'bjensen@EXAMPLE.COM'.  Canonical type values of 'work', 'home', and 'other'.",
+			MultiValued: true,
+			Mutability:  ReadWrite,
+			Name:        "emails",
+			Returned:    Default,
+			SubAttributes: []*Attribute{
+				{
+					Description: "Email addresses for the user.  The value SHOULD be canonicalized by the service provider, e.g., 'bjensen@example.com' instead of 'bjensen@EXAMPLE.COM'.  Canonical type values of 'work', 'home', and 'other'.",
+					Mutability:  ReadWrite,
+					Name:        "value",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "A human-readable name, primarily used for display purposes.  READ-ONLY.",
+					Mutability:  ReadWrite,
+					Name:        "display",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					CanonicalValues: []string{"work", "home", "other"},
+					Description:     "A label indicating the attribute's function, e.g., 'work' or 'home'.",
+					Mutability:      ReadWrite,
+					Name:            "type",
+					Returned:        Default,
+					Type:            StringType,
+					Uniqueness:      None,
+				},
+				{
+					Description: "A Boolean value indicating the 'primary' or preferred attribute value for this attribute, e.g., the preferred mailing address or primary email address.  The primary attribute value 'true' MUST appear no more than once.",
+					Mutability:  ReadWrite,
+					Name:        "primary",
+					Returned:    Default,
+					Type:        BooleanType,
+					Uniqueness:  None,
+				},
+			},
+			Type: ComplexType,
+		},
+		{
+			Description: "Phone numbers for the User.  The value SHOULD be canonicalized by the service provider according to the format specified in RFC 3966, e.g., 'tel:+1-201-555-0123'.  Canonical type values of 'work', 'home', 'mobile', 'fax', 'pager', and 'other'.",
+			MultiValued: true,
+			Mutability:  ReadWrite,
+			Name:        "phoneNumbers",
+			Returned:    Default,
+			SubAttributes: []*Attribute{
+				{
+					Description: "Phone number of the User.",
+					Mutability:  ReadWrite,
+					Name:        "value",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "A human-readable name, primarily used for display purposes.  READ-ONLY.",
+					Mutability:  ReadWrite,
+					Name:        "display",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					CanonicalValues: []string{"work", "home", "mobile", "fax", "pager", "other"},
+					Description:     "A label indicating the attribute's function, e.g., 'work', 'home', 'mobile'.",
+					Mutability:      ReadWrite,
+					Name:            "type",
+					Returned:        Default,
+					Type:            StringType,
+					Uniqueness:      None,
+				},
+				{
+					Description: "A Boolean value indicating the 'primary' or preferred attribute value for this attribute, e.g., the preferred phone number or primary phone number.  The primary attribute value 'true' MUST appear no more than once.",
+					Mutability:  ReadWrite,
+					Name:        "primary",
+					Returned:    Default,
+					Type:        BooleanType,
+					Uniqueness:  None,
+				},
+			},
+			Type: ComplexType,
+		},
+		{
+			Description: "Instant messaging addresses for the User.",
+			MultiValued: true,
+			Mutability:  ReadWrite,
+			Name:        "ims",
+			Returned:    Default,
+			SubAttributes: []*Attribute{
+				{
+					Description: "Instant messaging address for the User.",
+					Mutability:  ReadWrite,
+					Name:        "value",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "A human-readable name, primarily used for display purposes.  READ-ONLY.",
+					Mutability:  ReadWrite,
+					Name:        "display",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					CanonicalValues: []string{"aim", "gtalk", "icq", "xmpp", "msn", "skype", "qq", "yahoo"},
+					Description:     "A label indicating the attribute's function, e.g., 'aim', 'gtalk', 'xmpp'.",
+					Mutability:      ReadWrite,
+					Name:            "type",
+					Returned:        Default,
+					Type:            StringType,
+					Uniqueness:      None,
+				},
+				{
+					Description: "A Boolean value indicating the 'primary' or preferred attribute value for this attribute, e.g., the preferred messenger or primary messenger.  The primary attribute value 'true' MUST appear no more than once.",
+					Mutability:  ReadWrite,
+					Name:        "primary",
+					Returned:    Default,
+					Type:        BooleanType,
+					Uniqueness:  None,
+				},
+			},
+			Type: ComplexType,
+		},
+		{
+			Description: "URLs of photos of the User.",
+			MultiValued: true,
+			Mutability:  ReadWrite,
+			Name:        "photos",
+			Returned:    Default,
+			SubAttributes: []*Attribute{
+				{
+					Description:    "URL of a photo of the User.",
+					Mutability:     ReadWrite,
+					Name:           "value",
+					ReferenceTypes: []string{"external"},
+					Returned:       Default,
+					Type:           ReferenceType,
+					Uniqueness:     None,
+				},
+				{
+					Description: "A human-readable name, primarily used for display purposes.  READ-ONLY.",
+					Mutability:  ReadWrite,
+					Name:        "display",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					CanonicalValues: []string{"photo", "thumbnail"},
+					Description:     "A label indicating the attribute's function, i.e., 'photo' or 'thumbnail'.",
+					Mutability:      ReadWrite,
+					Name:            "type",
+					Returned:        Default,
+					Type:            StringType,
+					Uniqueness:      None,
+				},
+				{
+					Description: "A Boolean value indicating the 'primary' or preferred attribute value for this attribute, e.g., the preferred photo or thumbnail.  The primary attribute value 'true' MUST appear no more than once.",
+					Mutability:  ReadWrite,
+					Name:        "primary",
+					Returned:    Default,
+					Type:        BooleanType,
+					Uniqueness:  None,
+				},
+			},
+			Type: ComplexType,
+		},
+		{
+			Description: "A physical mailing address for this User.  Canonical type values of 'work', 'home', and 'other'.  This attribute is a complex type with the following sub-attributes.",
+			MultiValued: true,
+			Mutability:  ReadWrite,
+			Name:        "addresses",
+			Returned:    Default,
+			SubAttributes: []*Attribute{
+				{
+					Description: "The full mailing address, formatted for display or use with a mailing label.  This attribute MAY contain newlines.",
+					Mutability:  ReadWrite,
+					Name:        "formatted",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "The full street address component, which may include house number, street name, P.O. box, and multi-line extended street address information.  This attribute MAY contain newlines.",
+					Mutability:  ReadWrite,
+					Name:        "streetAddress",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "The city or locality component.",
+					Mutability:  ReadWrite,
+					Name:        "locality",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "The state or region component.",
+					Mutability:  ReadWrite,
+					Name:        "region",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "The zip code or postal code component.",
+					Mutability:  ReadWrite,
+					Name:        "postalCode",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "The country name component.",
+					Mutability:  ReadWrite,
+					Name:        "country",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					CanonicalValues: []string{"work", "home", "other"},
+					Description:     "A label indicating the attribute's function, e.g., 'work' or 'home'.",
+					Mutability:      ReadWrite,
+					Name:            "type",
+					Returned:        Default,
+					Type:            StringType,
+					Uniqueness:      None,
+				},
+				{
+					Description: "A Boolean value indicating the 'primary' or preferred attribute value for this attribute, e.g., the preferred mailing address.  The primary attribute value 'true' MUST appear no more than once.",
+					Mutability:  ReadWrite,
+					Name:        "primary",
+					Returned:    Default,
+					Type:        BooleanType,
+					Uniqueness:  None,
+				},
+			},
+			Type: ComplexType,
+		},
+		{
+			Description: "A list of groups to which the user belongs, either through direct membership, through nested groups, or dynamically calculated.",
+			MultiValued: true,
+			Mutability:  ReadOnly,
+			Name:        "groups",
+			Returned:    Default,
+			SubAttributes: []*Attribute{
+				{
+					Description: "The identifier of the User's group.",
+					Mutability:  ReadOnly,
+					Name:        "value",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description:    "The URI of the corresponding 'Group' resource to which the user belongs.",
+					Mutability:     ReadOnly,
+					Name:           "$ref",
+					ReferenceTypes: []string{"User", "Group"},
+					Returned:       Default,
+					Type:           ReferenceType,
+					Uniqueness:     None,
+				},
+				{
+					Description: "A human-readable name, primarily used for display purposes.  READ-ONLY.",
+					Mutability:  ReadOnly,
+					Name:        "display",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					CanonicalValues: []string{"direct", "indirect"},
+					Description:     "A label indicating the attribute's function, e.g., 'direct' or 'indirect'.",
+					Mutability:      ReadOnly,
+					Name:            "type",
+					Returned:        Default,
+					Type:            StringType,
+					Uniqueness:      None,
+				},
+			},
+			Type: ComplexType,
+		},
+		{
+			Description: "A list of entitlements for the User that represent a thing the User has.",
+			MultiValued: true,
+			Mutability:  ReadWrite,
+			Name:        "entitlements",
+			Returned:    Default,
+			SubAttributes: []*Attribute{
+				{
+					Description: "The value of an entitlement.",
+					Mutability:  ReadWrite,
+					Name:        "value",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "A human-readable name, primarily used for display purposes.  READ-ONLY.",
+					Mutability:  ReadWrite,
+					Name:        "display",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "A label indicating the attribute's function.",
+					Mutability:  ReadWrite,
+					Name:        "type",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "A Boolean value indicating the 'primary' or preferred attribute value for this attribute.  The primary attribute value 'true' MUST appear no more than once.",
+					Mutability:  ReadWrite,
+					Name:        "primary",
+					Returned:    Default,
+					Type:        BooleanType,
+					Uniqueness:  None,
+				},
+			},
+			Type: ComplexType,
+		},
+		{
+			Description: "A list of roles for the User that collectively represent who the User is, e.g., 'Student', 'Faculty'.",
+			MultiValued: true,
+			Mutability:  ReadWrite,
+			Name:        "roles",
+			Returned:    Default,
+			SubAttributes: []*Attribute{
+				{
+					Description: "The value of a role.",
+					Mutability:  ReadWrite,
+					Name:        "value",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "A human-readable name, primarily used for display purposes.  READ-ONLY.",
+					Mutability:  ReadWrite,
+					Name:        "display",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "A label indicating the attribute's function.",
+					Mutability:  ReadWrite,
+					Name:        "type",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "A Boolean value indicating the 'primary' or preferred attribute value for this attribute.  The primary attribute value 'true' MUST appear no more than once.",
+					Mutability:  ReadWrite,
+					Name:        "primary",
+					Returned:    Default,
+					Type:        BooleanType,
+					Uniqueness:  None,
+				},
+			},
+			Type: ComplexType,
+		},
+		{
+			Description: "A list of certificates issued to the User.",
+			MultiValued: true,
+			Mutability:  ReadWrite,
+			Name:        "x509Certificates",
+			Returned:    Default,
+			SubAttributes: []*Attribute{
+				{
+					Description: "The value of an X.509 certificate.",
+					Mutability:  ReadWrite,
+					Name:        "value",
+					Returned:    Default,
+					Type:        BinaryType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "A human-readable name, primarily used for display purposes.  READ-ONLY.",
+					Mutability:  ReadWrite,
+					Name:        "display",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "A label indicating the attribute's function.",
+					Mutability:  ReadWrite,
+					Name:        "type",
+					Returned:    Default,
+					Type:        StringType,
+					Uniqueness:  None,
+				},
+				{
+					Description: "A Boolean value indicating the 'primary' or preferred attribute value for this attribute.  The primary attribute value 'true' MUST appear no more than once.",
+					Mutability:  ReadWrite,
+					Name:        "primary",
+					Returned:    Default,
+					Type:        BooleanType,
+					Uniqueness:  None,
+				},
+			},
+			Type: ComplexType,
+		},
+	},
+}
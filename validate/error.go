@@ -0,0 +1,58 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ScimType is a detail error keyword as defined in RFC 7644 §3.12.
+type ScimType string
+
+const (
+	InvalidFilter ScimType = "invalidFilter"
+	TooMany       ScimType = "tooMany"
+	Uniqueness    ScimType = "uniqueness"
+	Mutability    ScimType = "mutability"
+	InvalidSyntax ScimType = "invalidSyntax"
+	InvalidPath   ScimType = "invalidPath"
+	NoTarget      ScimType = "noTarget"
+	InvalidValue  ScimType = "invalidValue"
+	InvalidVers   ScimType = "invalidVers"
+	Sensitive     ScimType = "sensitive"
+)
+
+// Error describes why the value of a single attribute is invalid.
+type Error struct {
+	// ScimType is the SCIM error type that should be returned to the client.
+	ScimType ScimType
+	// Path is the full path of the attribute, e.g. "emails[1].type".
+	Path string
+	// Detail is a human readable description of the error.
+	Detail string
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return e.Detail
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Detail)
+}
+
+// Errors is a list of errors, ordered by the path of the attribute.
+type Errors []*Error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func errorf(typ ScimType, path, format string, args ...interface{}) *Error {
+	return &Error{
+		ScimType: typ,
+		Path:     path,
+		Detail:   fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,236 @@
+package validate
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/memsql/scimtools/attributes"
+	"github.com/memsql/scimtools/schema"
+)
+
+// Sanitize prepares the resource of a create (POST) or replace (PUT) request according to the mutability of its
+// attributes (RFC 7644 §3.3 and §3.5.1). The stored resource is nil for create requests.
+//
+// Read only attributes (e.g. id and meta) are ignored: they are removed from the resource and, on replace, the stored
+// values are kept. Immutable attributes can only be set if they do not have a value yet, on replace the values of the
+// resource must match the stored values. Immutable attributes that are omitted keep their stored value.
+// Sub attributes of single valued complex attributes are sanitized the same way, multi valued complex attributes only
+// lose their read only sub attributes.
+//
+// Returns the sanitized copy of the resource, or Errors with the mutability type if an immutable attribute is changed.
+func Sanitize(resource, stored map[string]interface{}, s schema.ReferenceSchema, ext ...schema.ReferenceSchema) (map[string]interface{}, error) {
+	attrs := append(append([]*schema.Attribute{}, s.Attributes...), schema.CoreAttributes...)
+	sanitized, errs := sanitizeAttributes("", resource, stored, attrs, ext)
+	if len(errs) == 0 {
+		return sanitized, nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Path < errs[j].Path
+	})
+	return nil, errs
+}
+
+// sanitizeAttributes returns the sanitized copy of the resource. Attributes that are not defined are copied as is.
+func sanitizeAttributes(prefix string, resource, stored map[string]interface{}, attrs []*schema.Attribute, ext []schema.ReferenceSchema) (map[string]interface{}, Errors) {
+	var errs Errors
+	sanitized := make(map[string]interface{}, len(resource))
+	for _, key := range sortedKeys(resource) {
+		value := resource[key]
+		old, _ := attributes.Contains(key, stored)
+		if e, ok := extension(key, ext); ok {
+			m, ok := toMap(value)
+			if !ok {
+				// Invalid extensions are reported by Validate.
+				sanitized[key] = value
+				continue
+			}
+			oldMap, _ := toMap(old)
+			v, extErrs := sanitizeAttributes(key+":", m, oldMap, e.Attributes, nil)
+			errs = append(errs, extErrs...)
+			if len(v) != 0 {
+				sanitized[key] = v
+			}
+			continue
+		}
+
+		attribute := find(key, attrs)
+		if attribute == nil || attribute == schema.SchemasAttribute {
+			// The schemas of a resource change when extensions are added or removed.
+			sanitized[key] = value
+			continue
+		}
+		v, ok, err := sanitizeAttribute(prefix+key, value, old, attribute)
+		if err != nil {
+			errs = append(errs, err...)
+			continue
+		}
+		if ok {
+			sanitized[key] = v
+		}
+	}
+
+	// Keep the stored values of attributes that can not be changed by the client.
+	for _, key := range sortedKeys(stored) {
+		if _, ok := attributes.Contains(key, resource); ok {
+			continue
+		}
+		if _, ok := extension(key, ext); ok {
+			continue
+		}
+		attribute := find(key, attrs)
+		if attribute == nil {
+			continue
+		}
+		switch {
+		case attribute.Mutability == schema.ReadOnly || attribute.Mutability == schema.Immutable:
+			sanitized[key] = stored[key]
+		case attribute.Type == schema.ComplexType && !attribute.MultiValued:
+			if m, ok := toMap(stored[key]); ok {
+				if v, _ := sanitizeAttributes(prefix+key+".", map[string]interface{}{}, m, attribute.SubAttributes, nil); len(v) != 0 {
+					sanitized[key] = v
+				}
+			}
+		}
+	}
+	for _, e := range ext {
+		old, ok := attributes.Contains(e.ID, stored)
+		if _, found := attributes.Contains(e.ID, resource); found || !ok {
+			continue
+		}
+		if m, ok := toMap(old); ok {
+			if v, _ := sanitizeAttributes(e.ID+":", map[string]interface{}{}, m, e.Attributes, nil); len(v) != 0 {
+				sanitized[e.ID] = v
+			}
+		}
+	}
+	return sanitized, errs
+}
+
+// sanitizeAttribute returns the sanitized value of the attribute, false if the attribute should be removed.
+func sanitizeAttribute(path string, value, old interface{}, attribute *schema.Attribute) (interface{}, bool, Errors) {
+	switch attribute.Mutability {
+	case schema.ReadOnly:
+		return old, old != nil, nil
+	case schema.Immutable:
+		if old != nil && !equalValues(value, old, attribute) {
+			return nil, false, Errors{errorf(Mutability, path, "attribute is immutable")}
+		}
+		return value, true, nil
+	}
+	if attribute.Type != schema.ComplexType || value == nil {
+		return value, true, nil
+	}
+
+	if attribute.MultiValued {
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return value, true, nil
+		}
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			element := v.Index(i).Interface()
+			if m, ok := toMap(element); ok {
+				element = removeReadOnly(m, attribute.SubAttributes)
+			}
+			values[i] = element
+		}
+		return values, true, nil
+	}
+
+	m, ok := toMap(value)
+	if !ok {
+		return value, true, nil
+	}
+	oldMap, _ := toMap(old)
+	sanitized, errs := sanitizeAttributes(path+".", m, oldMap, attribute.SubAttributes, nil)
+	return sanitized, len(sanitized) != 0, errs
+}
+
+func removeReadOnly(m map[string]interface{}, attrs []*schema.Attribute) map[string]interface{} {
+	sanitized := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if attribute := find(k, attrs); attribute != nil && attribute.Mutability == schema.ReadOnly {
+			continue
+		}
+		sanitized[k] = v
+	}
+	return sanitized
+}
+
+// equalValues returns whether both values of the attribute are equal. Multi valued attributes are compared regardless
+// of the order of their values, strings are compared case insensitive unless the attribute is case exact.
+func equalValues(a, b interface{}, attribute *schema.Attribute) bool {
+	if !attribute.MultiValued {
+		return equalValue(a, b, attribute)
+	}
+	as, bs := attributes.ToSlice(a), attributes.ToSlice(b)
+	if len(as) != len(bs) {
+		return false
+	}
+	contains := func(values []interface{}, value interface{}) bool {
+		for _, v := range values {
+			if equalValue(v, value, attribute) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, v := range as {
+		if !contains(bs, v) {
+			return false
+		}
+	}
+	for _, v := range bs {
+		if !contains(as, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalValue(a, b interface{}, attribute *schema.Attribute) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	switch attribute.Type {
+	case schema.ComplexType:
+		am, ok := toMap(a)
+		if !ok {
+			return false
+		}
+		bm, ok := toMap(b)
+		if !ok {
+			return false
+		}
+		for _, sub := range attribute.SubAttributes {
+			av, _ := attributes.Contains(sub.Name, am)
+			bv, _ := attributes.Contains(sub.Name, bm)
+			if !equalValues(av, bv, sub) {
+				return false
+			}
+		}
+		return true
+	case schema.BooleanType:
+		return a == b
+	case schema.IntegerType, schema.DecimalType:
+		af, ok := attributes.ToFloat(a)
+		if !ok {
+			return false
+		}
+		bf, ok := attributes.ToFloat(b)
+		return ok && af == bf
+	}
+	as, ok := a.(string)
+	if !ok {
+		return reflect.DeepEqual(a, b)
+	}
+	bs, ok := b.(string)
+	if !ok {
+		return false
+	}
+	if attribute.CaseExact {
+		return as == bs
+	}
+	return strings.EqualFold(as, bs)
+}
@@ -0,0 +1,197 @@
+package validate_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/memsql/scimtools/schema"
+	"github.com/memsql/scimtools/validate"
+)
+
+var mutabilitySchema = schema.ReferenceSchema{
+	ID:   "urn:ietf:params:scim:schemas:core:2.0:User",
+	Name: "User",
+	Attributes: []*schema.Attribute{
+		{Name: "userName", Type: schema.StringType},
+		{Name: "employeeId", Type: schema.StringType, Mutability: schema.Immutable, CaseExact: true},
+		{Name: "groups", Type: schema.StringType, MultiValued: true, Mutability: schema.ReadOnly},
+		{
+			Name: "name",
+			Type: schema.ComplexType,
+			SubAttributes: []*schema.Attribute{
+				{Name: "givenName", Type: schema.StringType},
+				{Name: "formatted", Type: schema.StringType, Mutability: schema.ReadOnly},
+			},
+		},
+		{
+			Name:        "emails",
+			Type:        schema.ComplexType,
+			MultiValued: true,
+			SubAttributes: []*schema.Attribute{
+				{Name: "value", Type: schema.StringType},
+				{Name: "verified", Type: schema.BooleanType, Mutability: schema.ReadOnly},
+			},
+		},
+	},
+}
+
+var mutabilityExtension = schema.ReferenceSchema{
+	ID: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+	Attributes: []*schema.Attribute{
+		{Name: "employeeNumber", Type: schema.StringType, Mutability: schema.Immutable},
+		{Name: "costCenter", Type: schema.StringType},
+	},
+}
+
+func ExampleSanitize() {
+	stored := map[string]interface{}{
+		"id":       "2819c223",
+		"userName": "bjensen",
+	}
+	resource := map[string]interface{}{
+		"id":       "bjensen",
+		"userName": "barbara",
+	}
+	sanitized, _ := validate.Sanitize(resource, stored, schema.UserSchema)
+	fmt.Println(sanitized)
+
+	// Output:
+	// map[id:2819c223 userName:barbara]
+}
+
+func TestSanitize_create(t *testing.T) {
+	resource := map[string]interface{}{
+		"schemas":    []interface{}{mutabilitySchema.ID},
+		"id":         "0",
+		"meta":       map[string]interface{}{"resourceType": "User"},
+		"userName":   "bjensen",
+		"employeeId": "701984",
+		"groups":     []interface{}{"admin"},
+		"name":       map[string]interface{}{"givenName": "Barbara", "formatted": "Barbara Jensen"},
+		"emails":     []interface{}{map[string]interface{}{"value": "bjensen@example.com", "verified": true}},
+		"unknown":    "unknown",
+	}
+	sanitized, err := validate.Sanitize(resource, nil, mutabilitySchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"schemas":    []interface{}{mutabilitySchema.ID},
+		"userName":   "bjensen",
+		"employeeId": "701984",
+		"name":       map[string]interface{}{"givenName": "Barbara"},
+		"emails":     []interface{}{map[string]interface{}{"value": "bjensen@example.com"}},
+		"unknown":    "unknown",
+	}
+	if !reflect.DeepEqual(sanitized, expected) {
+		t.Errorf("expected %v, got %v", expected, sanitized)
+	}
+	if _, ok := resource["id"]; !ok {
+		t.Error("resource was modified")
+	}
+}
+
+func TestSanitize_replace(t *testing.T) {
+	stored := map[string]interface{}{
+		"id":         "0",
+		"userName":   "bjensen",
+		"employeeId": "701984",
+		"groups":     []interface{}{"admin"},
+		"name":       map[string]interface{}{"givenName": "Barbara", "formatted": "Barbara Jensen"},
+		mutabilityExtension.ID: map[string]interface{}{
+			"employeeNumber": "1",
+			"costCenter":     "4130",
+		},
+	}
+
+	for _, test := range []struct {
+		name     string
+		resource map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name: "unchanged",
+			resource: map[string]interface{}{
+				"id":         "1",
+				"USERNAME":   "babs",
+				"employeeId": "701984",
+				"groups":     []interface{}{"users"},
+				"name":       map[string]interface{}{"givenName": "Babs"},
+				mutabilityExtension.ID: map[string]interface{}{
+					"employeeNumber": "1",
+				},
+			},
+			expected: map[string]interface{}{
+				"id":         "0",
+				"USERNAME":   "babs",
+				"employeeId": "701984",
+				"groups":     []interface{}{"admin"},
+				"name":       map[string]interface{}{"givenName": "Babs", "formatted": "Barbara Jensen"},
+				mutabilityExtension.ID: map[string]interface{}{
+					"employeeNumber": "1",
+				},
+			},
+		},
+		{
+			name: "omitted",
+			resource: map[string]interface{}{
+				"userName": "bjensen",
+			},
+			expected: map[string]interface{}{
+				"id":         "0",
+				"userName":   "bjensen",
+				"employeeId": "701984",
+				"groups":     []interface{}{"admin"},
+				"name":       map[string]interface{}{"formatted": "Barbara Jensen"},
+				mutabilityExtension.ID: map[string]interface{}{
+					"employeeNumber": "1",
+				},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			sanitized, err := validate.Sanitize(test.resource, stored, mutabilitySchema, mutabilityExtension)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(sanitized, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, sanitized)
+			}
+		})
+	}
+}
+
+func TestSanitize_immutable(t *testing.T) {
+	stored := map[string]interface{}{
+		"employeeId": "701984",
+		mutabilityExtension.ID: map[string]interface{}{
+			"employeeNumber": "1",
+		},
+	}
+	resource := map[string]interface{}{
+		"employeeId": "701984-A",
+		mutabilityExtension.ID: map[string]interface{}{
+			"employeeNumber": float64(1),
+		},
+	}
+	_, err := validate.Sanitize(resource, stored, mutabilitySchema, mutabilityExtension)
+	var errs validate.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	for i, path := range []string{"employeeId", mutabilityExtension.ID + ":employeeNumber"} {
+		if errs[i].ScimType != validate.Mutability || errs[i].Path != path {
+			t.Errorf("unexpected error %v", errs[i])
+		}
+	}
+
+	// Immutable attributes can be set if they do not have a value yet.
+	if _, err := validate.Sanitize(resource, map[string]interface{}{}, mutabilitySchema, mutabilityExtension); err != nil {
+		t.Error(err)
+	}
+}
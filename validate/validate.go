@@ -0,0 +1,271 @@
+// Package validate checks SCIM resources against their reference schemas.
+package validate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/memsql/scimtools/attributes"
+	"github.com/memsql/scimtools/schema"
+)
+
+// Validate checks every attribute of the given resource against its definition in the reference schema.
+// Extension attributes are expected in a complex attribute named after the URN of the extension.
+// The common attributes (schemas, id, externalId and meta) are always allowed.
+//
+// Required attributes that are read only are not enforced, since clients can not provide them.
+// Returns Errors, ordered by attribute path, if the resource is invalid.
+func Validate(resource map[string]interface{}, s schema.ReferenceSchema, ext ...schema.ReferenceSchema) error {
+	var errs Errors
+	for _, key := range sortedKeys(resource) {
+		value := resource[key]
+		if e, ok := extension(key, ext); ok {
+			if value == nil {
+				continue
+			}
+			m, ok := toMap(value)
+			if !ok {
+				errs = append(errs, errorf(InvalidValue, key, "extension is not a complex attribute"))
+				continue
+			}
+			errs = append(errs, validateAttributes(key+":", m, e.Attributes)...)
+			continue
+		}
+
+		attribute := find(key, s.Attributes)
+		if attribute == nil {
+			attribute = find(key, schema.CoreAttributes)
+		}
+		if attribute == nil {
+			errs = append(errs, errorf(InvalidSyntax, key, "unknown attribute"))
+			continue
+		}
+		errs = append(errs, validateAttribute(key, value, attribute)...)
+	}
+	errs = append(errs, validateRequired("", resource, s.Attributes)...)
+	for _, e := range ext {
+		if value, ok := attributes.Contains(e.ID, resource); ok && value != nil {
+			if m, ok := toMap(value); ok {
+				errs = append(errs, validateRequired(e.ID+":", m, e.Attributes)...)
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Path < errs[j].Path
+	})
+	return errs
+}
+
+// Attribute checks the value of a single attribute against its definition, the path is used to report errors.
+// Returns Errors, ordered by attribute path, if the value is invalid.
+func Attribute(path string, value interface{}, attribute *schema.Attribute) error {
+	errs := validateAttribute(path, value, attribute)
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Path < errs[j].Path
+	})
+	return errs
+}
+
+func validateAttributes(prefix string, resource map[string]interface{}, attrs []*schema.Attribute) Errors {
+	var errs Errors
+	for _, key := range sortedKeys(resource) {
+		attribute := find(key, attrs)
+		if attribute == nil {
+			errs = append(errs, errorf(InvalidSyntax, prefix+key, "unknown attribute"))
+			continue
+		}
+		errs = append(errs, validateAttribute(prefix+key, resource[key], attribute)...)
+	}
+	return errs
+}
+
+func validateAttribute(path string, value interface{}, attribute *schema.Attribute) Errors {
+	if value == nil {
+		return nil
+	}
+
+	if !attribute.MultiValued {
+		return validateSingleAttribute(path, value, attribute)
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return Errors{errorf(InvalidValue, path, "expected a multi valued attribute, got %s", v.Kind())}
+	}
+	var errs Errors
+	for i := 0; i < v.Len(); i++ {
+		element := v.Index(i).Interface()
+		if element == nil {
+			continue
+		}
+		errs = append(errs, validateSingleAttribute(fmt.Sprintf("%s[%d]", path, i), element, attribute)...)
+	}
+	return errs
+}
+
+func validateSingleAttribute(path string, value interface{}, attribute *schema.Attribute) Errors {
+	v := reflect.ValueOf(value)
+	if k := v.Kind(); k == reflect.Slice || k == reflect.Array {
+		return Errors{errorf(InvalidValue, path, "expected a single valued attribute, got %s", k)}
+	}
+
+	switch attribute.Type {
+	case schema.StringType, schema.ReferenceType, "":
+		str, ok := value.(string)
+		if !ok {
+			return Errors{invalidType(path, attribute.Type, v)}
+		}
+		if len(attribute.CanonicalValues) != 0 && !canonical(str, attribute) {
+			return Errors{errorf(InvalidValue, path, "%q is not one of the canonical values %v", str, attribute.CanonicalValues)}
+		}
+	case schema.BooleanType:
+		if v.Kind() != reflect.Bool {
+			return Errors{invalidType(path, attribute.Type, v)}
+		}
+	case schema.DecimalType:
+		if n, ok := value.(json.Number); ok {
+			if _, err := n.Float64(); err != nil {
+				return Errors{invalidType(path, attribute.Type, v)}
+			}
+			return nil
+		}
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return Errors{invalidType(path, attribute.Type, v)}
+		}
+	case schema.IntegerType:
+		if n, ok := value.(json.Number); ok {
+			if _, err := n.Int64(); err != nil {
+				return Errors{invalidType(path, attribute.Type, v)}
+			}
+			return nil
+		}
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		case reflect.Float32, reflect.Float64:
+			// JSON numbers are decoded as floats.
+			if f := v.Float(); f != math.Trunc(f) || math.IsInf(f, 0) {
+				return Errors{invalidType(path, attribute.Type, v)}
+			}
+		default:
+			return Errors{invalidType(path, attribute.Type, v)}
+		}
+	case schema.DateTimeType:
+		str, ok := value.(string)
+		if !ok {
+			return Errors{invalidType(path, attribute.Type, v)}
+		}
+		if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+			return Errors{errorf(InvalidValue, path, "%q is not a valid dateTime", str)}
+		}
+	case schema.BinaryType:
+		str, ok := value.(string)
+		if !ok {
+			return Errors{invalidType(path, attribute.Type, v)}
+		}
+		if _, err := base64.StdEncoding.DecodeString(str); err != nil {
+			return Errors{errorf(InvalidValue, path, "value is not base64 encoded")}
+		}
+	case schema.ComplexType:
+		m, ok := toMap(value)
+		if !ok {
+			return Errors{invalidType(path, attribute.Type, v)}
+		}
+		errs := validateAttributes(path+".", m, attribute.SubAttributes)
+		return append(errs, validateRequired(path+".", m, attribute.SubAttributes)...)
+	default:
+		return Errors{errorf(InvalidValue, path, "unknown attribute type %s", attribute.Type)}
+	}
+	return nil
+}
+
+// validateRequired checks whether all required attributes are present.
+func validateRequired(prefix string, resource map[string]interface{}, attrs []*schema.Attribute) Errors {
+	var errs Errors
+	for _, attribute := range attrs {
+		if !attribute.Required || attribute.Mutability == schema.ReadOnly {
+			continue
+		}
+		if value, ok := attributes.Contains(attribute.Name, resource); !ok || value == nil {
+			errs = append(errs, errorf(InvalidValue, prefix+attribute.Name, "required attribute is missing"))
+		}
+	}
+	return errs
+}
+
+func canonical(value string, attribute *schema.Attribute) bool {
+	for _, c := range attribute.CanonicalValues {
+		if value == c || (!attribute.CaseExact && strings.EqualFold(value, c)) {
+			return true
+		}
+	}
+	return false
+}
+
+func extension(key string, ext []schema.ReferenceSchema) (schema.ReferenceSchema, bool) {
+	for _, e := range ext {
+		if strings.EqualFold(key, e.ID) {
+			return e, true
+		}
+	}
+	return schema.ReferenceSchema{}, false
+}
+
+// find returns the attribute with the given name (case insensitive), nil if not found.
+func find(name string, attrs []*schema.Attribute) *schema.Attribute {
+	for _, attribute := range attrs {
+		if strings.EqualFold(name, attribute.Name) {
+			return attribute
+		}
+	}
+	return nil
+}
+
+func invalidType(path string, typ schema.Type, v reflect.Value) *Error {
+	if typ == "" {
+		typ = schema.StringType
+	}
+	return errorf(InvalidValue, path, "expected a %s, got %s", typ, v.Kind())
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// toMap converts any map with string keys into a map[string]interface{}.
+func toMap(value interface{}) (map[string]interface{}, bool) {
+	if m, ok := value.(map[string]interface{}); ok {
+		return m, true
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	m := make(map[string]interface{}, v.Len())
+	for _, k := range v.MapKeys() {
+		m[k.String()] = v.MapIndex(k).Interface()
+	}
+	return m, true
+}
@@ -0,0 +1,174 @@
+package validate_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/memsql/scimtools/fuzz"
+	"github.com/memsql/scimtools/schema"
+	"github.com/memsql/scimtools/validate"
+)
+
+var (
+	userSchema = schema.ReferenceSchema{
+		ID:   "urn:ietf:params:scim:schemas:core:2.0:User",
+		Name: "User",
+		Attributes: []*schema.Attribute{
+			{Name: "userName", Type: schema.StringType, Required: true},
+			{Name: "active", Type: schema.BooleanType},
+			{Name: "age", Type: schema.IntegerType},
+			{Name: "birthday", Type: schema.DateTimeType},
+			{Name: "photo", Type: schema.BinaryType},
+			{
+				Name: "name",
+				Type: schema.ComplexType,
+				SubAttributes: []*schema.Attribute{
+					{Name: "givenName", Type: schema.StringType},
+					{Name: "familyName", Type: schema.StringType, Required: true},
+				},
+			},
+			{
+				Name:        "emails",
+				Type:        schema.ComplexType,
+				MultiValued: true,
+				SubAttributes: []*schema.Attribute{
+					{Name: "value", Type: schema.StringType},
+					{Name: "type", Type: schema.StringType, CanonicalValues: []string{"work", "home", "other"}},
+					{Name: "primary", Type: schema.BooleanType},
+				},
+			},
+		},
+	}
+	enterpriseSchema = schema.ReferenceSchema{
+		ID:   "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+		Name: "EnterpriseUser",
+		Attributes: []*schema.Attribute{
+			{Name: "employeeNumber", Type: schema.StringType},
+		},
+	}
+)
+
+func ExampleValidate() {
+	err := validate.Validate(map[string]interface{}{
+		"userName": "bjensen",
+		"emails": []interface{}{
+			map[string]interface{}{"value": "bjensen@example.com", "type": "work"},
+			map[string]interface{}{"value": "babs@example.com", "type": "mobile"},
+		},
+	}, userSchema)
+	fmt.Println(err)
+
+	var errs validate.Errors
+	if errors.As(err, &errs) {
+		fmt.Println(errs[0].ScimType)
+	}
+
+	// Output:
+	// emails[1].type: "mobile" is not one of the canonical values [work home other]
+	// invalidValue
+}
+
+func TestValidate(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		resource map[string]interface{}
+		errs     map[string]validate.ScimType
+	}{
+		{
+			name: "valid",
+			resource: map[string]interface{}{
+				"schemas":  []interface{}{userSchema.ID},
+				"id":       "2819c223",
+				"UserName": "bjensen",
+				"active":   true,
+				"age":      float64(42),
+				"birthday": "1980-01-01T00:00:00Z",
+				"photo":    "aGVsbG8=",
+				"name":     map[string]interface{}{"familyName": "Jensen"},
+				"emails": []map[string]interface{}{
+					{"value": "bjensen@example.com", "type": "Work", "primary": true},
+				},
+				enterpriseSchema.ID: map[string]interface{}{
+					"employeeNumber": "701984",
+				},
+			},
+		},
+		{
+			name:     "required",
+			resource: map[string]interface{}{"name": map[string]interface{}{"givenName": "Barbara"}},
+			errs: map[string]validate.ScimType{
+				"userName":        validate.InvalidValue,
+				"name.familyName": validate.InvalidValue,
+			},
+		},
+		{
+			name: "unknown",
+			resource: map[string]interface{}{
+				"userName":          "bjensen",
+				"nickName":          "Babs",
+				"emails":            []interface{}{map[string]interface{}{"display": "Babs"}},
+				enterpriseSchema.ID: map[string]interface{}{"manager": "Jim"},
+			},
+			errs: map[string]validate.ScimType{
+				"nickName":                       validate.InvalidSyntax,
+				"emails[0].display":              validate.InvalidSyntax,
+				enterpriseSchema.ID + ":manager": validate.InvalidSyntax,
+			},
+		},
+		{
+			name: "types",
+			resource: map[string]interface{}{
+				"userName": 1,
+				"active":   "true",
+				"age":      1.5,
+				"birthday": "yesterday",
+				"photo":    "!",
+				"name":     "Barbara Jensen",
+				"emails":   map[string]interface{}{"value": "bjensen@example.com"},
+			},
+			errs: map[string]validate.ScimType{
+				"userName": validate.InvalidValue,
+				"active":   validate.InvalidValue,
+				"age":      validate.InvalidValue,
+				"birthday": validate.InvalidValue,
+				"photo":    validate.InvalidValue,
+				"name":     validate.InvalidValue,
+				"emails":   validate.InvalidValue,
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := validate.Validate(test.resource, userSchema, enterpriseSchema)
+			if len(test.errs) == 0 {
+				if err != nil {
+					t.Fatalf("no error expected, got %q", err)
+				}
+				return
+			}
+
+			var errs validate.Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected validation errors, got %v", err)
+			}
+			if len(errs) != len(test.errs) {
+				t.Errorf("expected %d errors, got %d: %s", len(test.errs), len(errs), errs)
+			}
+			for _, e := range errs {
+				if typ, ok := test.errs[e.Path]; !ok || typ != e.ScimType {
+					t.Errorf("unexpected error: %s (%s)", e, e.ScimType)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_fuzz(t *testing.T) {
+	f := fuzz.New(schema.UserSchema).NumElements(0, 3)
+	for i := 0; i < 100; i++ {
+		resource := f.Fuzz()
+		if err := validate.Validate(resource, schema.UserSchema, schema.EnterpriseUserSchema); err != nil {
+			t.Fatalf("%v: %s", resource, err)
+		}
+	}
+}